@@ -1,5 +1,5 @@
 module github.com/ZiplEix/c_parser
 
-go 1.22.1
+go 1.23
 
-require github.com/sanity-io/litter v1.5.5 // indirect
+require github.com/sanity-io/litter v1.5.5
@@ -1,23 +1,49 @@
 package ast
 
-import "github.com/ZiplEix/c_parser/src/helpers"
+import (
+	"github.com/ZiplEix/c_parser/src/helpers"
+	"github.com/ZiplEix/c_parser/src/source"
+)
 
+// Node is implemented by every node of the AST. Pos is the position of its
+// first byte and End the position right after its last byte.
+type Node interface {
+	Pos() source.Position
+	End() source.Position
+}
+
+// Span is the part of the source a node comes from, embedding it makes a
+// struct implement Node.
+type Span struct {
+	From source.Position
+	To   source.Position
+}
+
+func (s Span) Pos() source.Position { return s.From }
+func (s Span) End() source.Position { return s.To }
+
+// Stmt is implemented by the statement nodes. Packages can define their own
+// statements by implementing StmtNode, see Walk for their children.
 type Stmt interface {
-	stmt()
+	Node
+	StmtNode()
 }
 
+// Expr is implemented by the expression nodes. Packages can define their own
+// expressions by implementing ExprNode, see Walk for their children.
 type Expr interface {
-	expr()
+	Node
+	ExprNode()
 }
 
 type Type interface {
-	_type()
+	TypeNode()
 }
 
-func ExpectExpr[T Expr](expr Expr) T {
+func ExpectExpr[T Expr](expr Expr) (T, error) {
 	return helpers.ExpectType[T](expr)
 }
 
-func ExpectStmt[T Stmt](expr Stmt) T {
+func ExpectStmt[T Stmt](expr Stmt) (T, error) {
 	return helpers.ExpectType[T](expr)
 }
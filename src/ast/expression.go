@@ -1,55 +1,192 @@
 package ast
 
-import "github.com/ZiplEix/c_parser/src/lexer"
+import (
+	"github.com/ZiplEix/c_parser/src/lexer"
+	"github.com/ZiplEix/c_parser/src/source"
+)
+
+// BadExpr is a placeholder for an expression containing a syntax error.
+type BadExpr struct {
+	Span
+}
+
+func (e BadExpr) ExprNode() {}
 
 //
 // LITERAL EXPRESSION
 //
 
+// IntegerExpr is an integer constant whose C type is signed. Raw is the
+// literal as written, Type is int, long, long long or _BitInt depending on
+// the suffix and on the value.
 type IntegerExpr struct {
-	Value int64
+	Span
+	Value  int64
+	Raw    string
+	Base   int
+	Suffix string
+	Type   VarType
 }
 
-func (e IntegerExpr) expr() {}
+func (e IntegerExpr) ExprNode() {}
 
+// FloatExpr is a floating constant. Raw is the literal as written and Type is
+// FLOAT, DOUBLE or LONG_DOUBLE depending on its suffix.
 type FloatExpr struct {
+	Span
 	Value float64
+	Raw   string
+	Type  VarType
 }
 
-func (e FloatExpr) expr() {}
+func (e FloatExpr) ExprNode() {}
 
+// UnsignedIntegerExpr is an integer constant whose C type is unsigned, either
+// because of a u suffix or because the value does not fit the signed types.
 type UnsignedIntegerExpr struct {
-	Value uint64
+	Span
+	Value  uint64
+	Raw    string
+	Base   int
+	Suffix string
+	Type   VarType
 }
 
-func (e UnsignedIntegerExpr) expr() {}
+func (e UnsignedIntegerExpr) ExprNode() {}
 
+// CharacterExpr is a character constant. Value is the value computed by the C
+// compiler, Raw is the constant as written, quotes and prefix included.
 type CharacterExpr struct {
-	Value string
+	Span
+	Value  int64
+	Raw    string
+	Prefix string
 }
 
-func (e CharacterExpr) expr() {}
+func (e CharacterExpr) ExprNode() {}
 
+// StringExpr is a string literal. Value holds the decoded content (escape
+// sequences replaced by the characters they stand for), Raw the literal as
+// written, quotes and prefix included. Adjacent literals are concatenated into
+// a single StringExpr, Parts keeps each of them.
 type StringExpr struct {
-	Value string
+	Span
+	Value  string
+	Raw    string
+	Prefix string
+	Parts  []StringPart
+}
+
+// StringPart is one of the adjacent literals making up a StringExpr.
+type StringPart struct {
+	Raw    string
+	Prefix string
+	Start  source.Position
+	End    source.Position
 }
 
-func (e StringExpr) expr() {}
+func (e StringExpr) ExprNode() {}
 
 type SymbolExpr struct {
+	Span
 	Value string
 }
 
-func (e SymbolExpr) expr() {}
+func (e SymbolExpr) ExprNode() {}
 
 //
 // BINARY EXPRESSION
 //
 
 type BinaryExpr struct {
+	Span
 	Left     Expr
 	Operator lexer.Token
 	Right    Expr
 }
 
-func (e BinaryExpr) expr() {}
+func (e BinaryExpr) ExprNode() {}
+
+// ParenExpr is an expression between parentheses.
+type ParenExpr struct {
+	Span
+	X Expr
+}
+
+func (e ParenExpr) ExprNode() {}
+
+//
+// CALL EXPRESSION
+//
+
+// CallExpr is a function call, Func is the called expression: a function
+// name, or any expression giving a function pointer.
+type CallExpr struct {
+	Span
+	Func Expr
+	Args []Expr
+}
+
+func (e CallExpr) ExprNode() {}
+
+//
+// MEMBER EXPRESSIONS
+//
+
+// MemberExpr is the access to a member of a structure or union, Operator
+// being . for p.x or -> for p->x.
+type MemberExpr struct {
+	Span
+	X        Expr
+	Operator lexer.Token
+	Member   string
+}
+
+func (e MemberExpr) ExprNode() {}
+
+// IndexExpr is an array subscript: X[Index].
+type IndexExpr struct {
+	Span
+	X     Expr
+	Index Expr
+}
+
+func (e IndexExpr) ExprNode() {}
+
+//
+// UNARY EXPRESSIONS
+//
+
+// UnaryExpr is a prefix operator applied to its operand: -x, +x, !x, ~x, *p,
+// &v, ++i or --i.
+type UnaryExpr struct {
+	Span
+	Operator lexer.Token
+	Operand  Expr
+}
+
+func (e UnaryExpr) ExprNode() {}
+
+// PostfixExpr is a postfix increment or decrement: i++ or i--.
+type PostfixExpr struct {
+	Span
+	Operand  Expr
+	Operator lexer.Token
+}
+
+func (e PostfixExpr) ExprNode() {}
+
+//
+// ASSIGNMENT EXPRESSION
+//
+
+// AssignmentExpr is an assignment, the operator being = or a compound one
+// like += or <<=.
+type AssignmentExpr struct {
+	Span
+	Assignee Expr
+	Operator lexer.Token
+	Value    Expr
+}
+
+func (e AssignmentExpr) ExprNode() {}
@@ -0,0 +1,468 @@
+package ast
+
+import "fmt"
+
+// An ApplyFunc is invoked by Apply for each node, with a Cursor on it.
+type ApplyFunc func(*Cursor) bool
+
+// Cursor describes a node encountered during Apply and allows to change it.
+type Cursor struct {
+	parent Node
+	name   string
+	index  int
+	node   Node
+
+	inList   bool
+	replaced bool
+	deleted  bool
+	before   []Node
+	after    []Node
+}
+
+// Node returns the current node, the one given to Replace if it was called.
+func (c *Cursor) Node() Node { return c.node }
+
+// Parent returns the parent of the current node, as it was before the
+// changes of Apply. It is nil for the root.
+func (c *Cursor) Parent() Node { return c.parent }
+
+// Name returns the name of the field of the parent holding the current node,
+// like "Body" or "Left". It is "" for the root.
+func (c *Cursor) Name() string { return c.name }
+
+// Index returns the index of the current node in the list of its parent
+// before the changes of Apply, or -1 if the node is not in a list.
+func (c *Cursor) Index() int { return c.index }
+
+// Replace replaces the current node by n. The children of n are the ones
+// walked after pre returns.
+func (c *Cursor) Replace(n Node) {
+	if n == nil {
+		panic("ast.Cursor.Replace: nil node, use Delete")
+	}
+	c.node = n
+	c.replaced = true
+	c.deleted = false
+}
+
+// Delete removes the current node from its list. The children of a node
+// deleted in pre are not walked and post is not called for it.
+func (c *Cursor) Delete() {
+	c.checkList("Delete")
+	c.deleted = true
+}
+
+// InsertBefore inserts n before the current node of a list. Inserted nodes
+// are not walked.
+func (c *Cursor) InsertBefore(n Node) {
+	c.checkList("InsertBefore")
+	c.before = append(c.before, n)
+}
+
+// InsertAfter inserts n after the current node of a list, the nodes inserted
+// by successive calls being in order. Inserted nodes are not walked.
+func (c *Cursor) InsertAfter(n Node) {
+	c.checkList("InsertAfter")
+	c.after = append(c.after, n)
+}
+
+func (c *Cursor) checkList(method string) {
+	if !c.inList {
+		panic(fmt.Sprintf("ast.Cursor.%s: %s.%s is not a list", method, typeName(c.parent), c.name))
+	}
+}
+
+// Apply traverses an AST recursively, calling pre for each node before its
+// children and post after them, and returns the resulting AST. If pre returns
+// false, the children of the node are not walked and post is not called for
+// it. If post returns false, the traversal stops.
+//
+// The given AST is never modified: the nodes changed through the Cursor and
+// their ancestors are copies, the unchanged subtrees being shared with the
+// original AST. Positions are kept, replacement nodes have their own.
+// Nodes of other packages are not walked into.
+func Apply(root Node, pre, post ApplyFunc) Node {
+	a := &application{pre: pre, post: post}
+
+	result, _ := a.apply(&Cursor{index: -1, node: root})
+	if len(result) != 1 {
+		panic("ast.Apply: the root cannot be deleted or have siblings")
+	}
+	return result[0]
+}
+
+type application struct {
+	pre, post ApplyFunc
+	stopped   bool
+}
+
+// apply runs pre, the children and post on the node of the cursor. It returns
+// the nodes taking its place, and whether they are different from it.
+func (a *application) apply(c *Cursor) ([]Node, bool) {
+	if !a.stopped && (a.pre == nil || a.pre(c)) && !c.deleted {
+		node, changed := a.applyChildren(c.node)
+		if changed {
+			c.node = node
+			c.replaced = true
+		}
+
+		if a.post != nil && !a.stopped && !a.post(c) {
+			a.stopped = true
+		}
+	}
+
+	if c.deleted {
+		return append(c.before, c.after...), true
+	}
+
+	result := append(c.before, c.node)
+	result = append(result, c.after...)
+
+	return result, c.replaced || len(result) != 1
+}
+
+// applyField applies the traversal on a node stored in a field of parent.
+func (a *application) applyField(parent Node, name string, node Node) (Node, bool) {
+	if node == nil {
+		return nil, false
+	}
+
+	result, changed := a.apply(&Cursor{parent: parent, name: name, index: -1, node: node})
+	return result[0], changed
+}
+
+// applyList applies the traversal on the nodes of a list of parent.
+func applyList[T any](a *application, parent Node, name string, list []T, toNode func(T) Node, fromNode func(Node) T) ([]T, bool) {
+	out := make([]T, 0, len(list))
+	changed := false
+
+	for i, element := range list {
+		result, elementChanged := a.apply(&Cursor{parent: parent, name: name, index: i, node: toNode(element), inList: true})
+		if !elementChanged {
+			out = append(out, element)
+			continue
+		}
+
+		changed = true
+		for _, node := range result {
+			out = append(out, fromNode(node))
+		}
+	}
+
+	if !changed {
+		return list, false
+	}
+	return out, true
+}
+
+func (a *application) applyStmts(parent Node, name string, list []Stmt) ([]Stmt, bool) {
+	if list == nil {
+		return nil, false
+	}
+
+	return applyList(a, parent, name, list,
+		func(stmt Stmt) Node { return stmt },
+		func(node Node) Stmt { return asStmt(parent, name, node) })
+}
+
+func (a *application) applyStmt(parent Node, name string, stmt Stmt) (Stmt, bool) {
+	if stmt == nil {
+		return nil, false
+	}
+
+	node, changed := a.applyField(parent, name, stmt)
+	if !changed {
+		return stmt, false
+	}
+	return asStmt(parent, name, node), true
+}
+
+func (a *application) applyExpr(parent Node, name string, expr Expr) (Expr, bool) {
+	if expr == nil {
+		return nil, false
+	}
+
+	node, changed := a.applyField(parent, name, expr)
+	if !changed {
+		return expr, false
+	}
+	return asExpr(parent, name, node), true
+}
+
+func (a *application) applyExprs(parent Node, name string, list []Expr) ([]Expr, bool) {
+	return applyList(a, parent, name, list,
+		func(expr Expr) Node { return expr },
+		func(node Node) Expr { return asExpr(parent, name, node) })
+}
+
+func (a *application) applyParameters(parent Node, list []Parameter) ([]Parameter, bool) {
+	return applyList(a, parent, "Parameters", list,
+		func(param Parameter) Node { return &param },
+		func(node Node) Parameter {
+			param, ok := node.(*Parameter)
+			if !ok {
+				panic(fmt.Sprintf("ast.Apply: %T cannot be stored in %s.Parameters", node, typeName(parent)))
+			}
+			return *param
+		})
+}
+
+func (a *application) applyCaseLabels(parent Node, list []CaseLabel) ([]CaseLabel, bool) {
+	return applyList(a, parent, "Labels", list,
+		func(label CaseLabel) Node { return &label },
+		func(node Node) CaseLabel {
+			label, ok := node.(*CaseLabel)
+			if !ok {
+				panic(fmt.Sprintf("ast.Apply: %T cannot be stored in %s.Labels", node, typeName(parent)))
+			}
+			return *label
+		})
+}
+
+// applyChildren applies the traversal on the children of node. When one of
+// them changed, it returns a copy of node holding the new children.
+func (a *application) applyChildren(node Node) (Node, bool) {
+	switch n := node.(type) {
+	// Expressions
+	case BinaryExpr:
+		left, leftChanged := a.applyExpr(n, "Left", n.Left)
+		right, rightChanged := a.applyExpr(n, "Right", n.Right)
+		if !leftChanged && !rightChanged {
+			return n, false
+		}
+		n.Left, n.Right = left, right
+		return n, true
+
+	case ParenExpr:
+		x, changed := a.applyExpr(n, "X", n.X)
+		if !changed {
+			return n, false
+		}
+		n.X = x
+		return n, true
+
+	case UnaryExpr:
+		operand, changed := a.applyExpr(n, "Operand", n.Operand)
+		if !changed {
+			return n, false
+		}
+		n.Operand = operand
+		return n, true
+
+	case PostfixExpr:
+		operand, changed := a.applyExpr(n, "Operand", n.Operand)
+		if !changed {
+			return n, false
+		}
+		n.Operand = operand
+		return n, true
+
+	case AssignmentExpr:
+		assignee, assigneeChanged := a.applyExpr(n, "Assignee", n.Assignee)
+		value, valueChanged := a.applyExpr(n, "Value", n.Value)
+		if !assigneeChanged && !valueChanged {
+			return n, false
+		}
+		n.Assignee, n.Value = assignee, value
+		return n, true
+
+	case CallExpr:
+		fun, funChanged := a.applyExpr(n, "Func", n.Func)
+		args, argsChanged := a.applyExprs(n, "Args", n.Args)
+		if !funChanged && !argsChanged {
+			return n, false
+		}
+		n.Func, n.Args = fun, args
+		return n, true
+
+	case MemberExpr:
+		x, changed := a.applyExpr(n, "X", n.X)
+		if !changed {
+			return n, false
+		}
+		n.X = x
+		return n, true
+
+	case IndexExpr:
+		x, xChanged := a.applyExpr(n, "X", n.X)
+		index, indexChanged := a.applyExpr(n, "Index", n.Index)
+		if !xChanged && !indexChanged {
+			return n, false
+		}
+		n.X, n.Index = x, index
+		return n, true
+
+	// Statements
+	case *File:
+		body, changed := a.applyStmts(n, "Body", n.Body)
+		if !changed {
+			return n, false
+		}
+		clone := *n
+		clone.Body = body
+		return &clone, true
+
+	case *BlockStmt:
+		body, changed := a.applyStmts(n, "Body", n.Body)
+		if !changed {
+			return n, false
+		}
+		clone := *n
+		clone.Body = body
+		return &clone, true
+
+	case *ExprStmt:
+		expr, changed := a.applyExpr(n, "Expr", n.Expr)
+		if !changed {
+			return n, false
+		}
+		clone := *n
+		clone.Expr = expr
+		return &clone, true
+
+	case *VarDeclarationStmt:
+		expr, changed := a.applyExpr(n, "AssignedExpr", n.AssignedExpr)
+		if !changed {
+			return n, false
+		}
+		clone := *n
+		clone.AssignedExpr = expr
+		return &clone, true
+
+	case *ReturnStmt:
+		expr, changed := a.applyExpr(n, "Expr", n.Expr)
+		if !changed {
+			return n, false
+		}
+		clone := *n
+		clone.Expr = expr
+		return &clone, true
+
+	case *IfStmt:
+		cond, condChanged := a.applyExpr(n, "Cond", n.Cond)
+		then, thenChanged := a.applyStmt(n, "Then", n.Then)
+		elseStmt, elseChanged := a.applyStmt(n, "Else", n.Else)
+		if !condChanged && !thenChanged && !elseChanged {
+			return n, false
+		}
+		clone := *n
+		clone.Cond, clone.Then, clone.Else = cond, then, elseStmt
+		return &clone, true
+
+	case *WhileStmt:
+		cond, condChanged := a.applyExpr(n, "Cond", n.Cond)
+		body, bodyChanged := a.applyStmt(n, "Body", n.Body)
+		if !condChanged && !bodyChanged {
+			return n, false
+		}
+		clone := *n
+		clone.Cond, clone.Body = cond, body
+		return &clone, true
+
+	case *DoWhileStmt:
+		body, bodyChanged := a.applyStmt(n, "Body", n.Body)
+		cond, condChanged := a.applyExpr(n, "Cond", n.Cond)
+		if !bodyChanged && !condChanged {
+			return n, false
+		}
+		clone := *n
+		clone.Body, clone.Cond = body, cond
+		return &clone, true
+
+	case *ForStmt:
+		init, initChanged := a.applyStmt(n, "Init", n.Init)
+		cond, condChanged := a.applyExpr(n, "Cond", n.Cond)
+		post, postChanged := a.applyExpr(n, "Post", n.Post)
+		body, bodyChanged := a.applyStmt(n, "Body", n.Body)
+		if !initChanged && !condChanged && !postChanged && !bodyChanged {
+			return n, false
+		}
+		clone := *n
+		clone.Init, clone.Cond, clone.Post, clone.Body = init, cond, post, body
+		return &clone, true
+
+	case *SwitchStmt:
+		tag, tagChanged := a.applyExpr(n, "Tag", n.Tag)
+		body, bodyChanged := a.applyStmts(n, "Body", n.Body)
+		if !tagChanged && !bodyChanged {
+			return n, false
+		}
+		clone := *n
+		clone.Tag, clone.Body = tag, body
+		return &clone, true
+
+	case *CaseClause:
+		labels, labelsChanged := a.applyCaseLabels(n, n.Labels)
+		body, bodyChanged := a.applyStmts(n, "Body", n.Body)
+		if !labelsChanged && !bodyChanged {
+			return n, false
+		}
+		clone := *n
+		clone.Labels, clone.Body = labels, body
+		return &clone, true
+
+	case *CaseLabel:
+		value, valueChanged := a.applyExpr(n, "Value", n.Value)
+		high, highChanged := a.applyExpr(n, "High", n.High)
+		if !valueChanged && !highChanged {
+			return n, false
+		}
+		clone := *n
+		clone.Value, clone.High = value, high
+		return &clone, true
+
+	case *LabeledStmt:
+		stmt, changed := a.applyStmt(n, "Stmt", n.Stmt)
+		if !changed {
+			return n, false
+		}
+		clone := *n
+		clone.Stmt = stmt
+		return &clone, true
+
+	case *IncluderStmt:
+		decls, changed := a.applyStmts(n, "Decls", n.Decls)
+		if !changed {
+			return n, false
+		}
+		clone := *n
+		clone.Decls = decls
+		return &clone, true
+
+	case *FunctionDeclarationStmt:
+		params, paramsChanged := a.applyParameters(n, n.Parameters)
+		body, bodyChanged := a.applyStmts(n, "Body", n.Body)
+		if !paramsChanged && !bodyChanged {
+			return n, false
+		}
+		clone := *n
+		clone.Parameters, clone.Body = params, body
+		return &clone, true
+	}
+
+	// leaves and nodes of other packages
+	return node, false
+}
+
+func asStmt(parent Node, name string, node Node) Stmt {
+	stmt, ok := node.(Stmt)
+	if !ok {
+		panic(fmt.Sprintf("ast.Apply: %T is not a statement and cannot be stored in %s.%s", node, typeName(parent), name))
+	}
+	return stmt
+}
+
+func asExpr(parent Node, name string, node Node) Expr {
+	expr, ok := node.(Expr)
+	if !ok {
+		panic(fmt.Sprintf("ast.Apply: %T is not an expression and cannot be stored in %s.%s", node, typeName(parent), name))
+	}
+	return expr
+}
+
+func typeName(node Node) string {
+	if node == nil {
+		return "root"
+	}
+	return fmt.Sprintf("%T", node)
+}
@@ -0,0 +1,259 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ZiplEix/c_parser/src/lexer"
+	"github.com/ZiplEix/c_parser/src/source"
+)
+
+func span(from, to int) Span {
+	return Span{From: source.Position{Offset: from}, To: source.Position{Offset: to}}
+}
+
+// newFile builds the AST of
+//
+//	int f(int x) {
+//		x + 1;
+//		{ y; }
+//		return x;
+//	}
+//	int z;
+func newFile() *File {
+	return &File{
+		Span: span(0, 60),
+		Body: []Stmt{
+			&FunctionDeclarationStmt{
+				Span:       span(0, 50),
+				Name:       "f",
+				Parameters: []Parameter{{Span: span(6, 11), Name: "x"}},
+				Body: []Stmt{
+					&ExprStmt{
+						Span: span(15, 21),
+						Expr: BinaryExpr{
+							Span:     span(15, 20),
+							Left:     SymbolExpr{Span: span(15, 16), Value: "x"},
+							Operator: lexer.Token{Kind: lexer.PLUS, Value: "+"},
+							Right:    IntegerExpr{Span: span(19, 20), Value: 1, Raw: "1"},
+						},
+					},
+					&BlockStmt{
+						Span: span(23, 29),
+						Body: []Stmt{
+							&ExprStmt{Span: span(25, 27), Expr: SymbolExpr{Span: span(25, 26), Value: "y"}},
+						},
+					},
+					&ReturnStmt{Span: span(31, 40), Expr: SymbolExpr{Span: span(38, 39), Value: "x"}},
+				},
+			},
+			&VarDeclarationStmt{Span: span(52, 58), Name: "z"},
+		},
+	}
+}
+
+func functionBody(node Node) []Stmt {
+	return node.(*File).Body[0].(*FunctionDeclarationStmt).Body
+}
+
+// checkUnchanged fails when the original AST differs from a new one.
+func checkUnchanged(t *testing.T, original *File) {
+	t.Helper()
+	if !reflect.DeepEqual(original, newFile()) {
+		t.Error("the original AST was modified by Apply")
+	}
+}
+
+func TestApplyWithoutChanges(t *testing.T) {
+	file := newFile()
+	visited := 0
+
+	result := Apply(file, func(c *Cursor) bool {
+		visited++
+		return true
+	}, nil)
+
+	if result != Node(file) {
+		t.Error("Apply without changes did not return the original root")
+	}
+	if visited != 13 {
+		t.Errorf("visited %d nodes, want 13", visited)
+	}
+	checkUnchanged(t, file)
+}
+
+func TestApplyReplaceExpr(t *testing.T) {
+	file := newFile()
+	replacement := IntegerExpr{Span: span(15, 16), Value: 42, Raw: "42"}
+
+	result := Apply(file, func(c *Cursor) bool {
+		if symbol, ok := c.Node().(SymbolExpr); ok && symbol.Value == "x" && c.Name() == "Left" {
+			if _, ok := c.Parent().(BinaryExpr); !ok || c.Index() != -1 {
+				t.Errorf("got parent %T and index %d", c.Parent(), c.Index())
+			}
+			c.Replace(replacement)
+		}
+		return true
+	}, nil)
+
+	body := functionBody(result)
+	binary := body[0].(*ExprStmt).Expr.(BinaryExpr)
+	if !reflect.DeepEqual(binary.Left, replacement) {
+		t.Errorf("got left operand %#v, want %#v", binary.Left, replacement)
+	}
+	if binary.Span != span(15, 20) {
+		t.Errorf("the span of the copied binary expression changed to %v", binary.Span)
+	}
+
+	// the unchanged subtrees are shared with the original AST
+	if body[1] != functionBody(file)[1] || result.(*File).Body[1] != file.Body[1] {
+		t.Error("unchanged statements were copied")
+	}
+	if body[0] == functionBody(file)[0] {
+		t.Error("the changed statement was not copied")
+	}
+
+	checkUnchanged(t, file)
+}
+
+func TestApplyDelete(t *testing.T) {
+	file := newFile()
+
+	result := Apply(file, func(c *Cursor) bool {
+		if _, ok := c.Node().(*BlockStmt); ok {
+			if c.Name() != "Body" || c.Index() != 1 {
+				t.Errorf("got name %q and index %d", c.Name(), c.Index())
+			}
+			c.Delete()
+		}
+		return true
+	}, func(c *Cursor) bool {
+		if _, ok := c.Node().(*BlockStmt); ok {
+			t.Error("post was called for a deleted node")
+		}
+		return true
+	})
+
+	body := functionBody(result)
+	if len(body) != 2 {
+		t.Fatalf("got %d statements, want 2", len(body))
+	}
+	if _, ok := body[1].(*ReturnStmt); !ok {
+		t.Errorf("got %T after the deleted statement, want *ast.ReturnStmt", body[1])
+	}
+
+	checkUnchanged(t, file)
+}
+
+func TestApplyInsert(t *testing.T) {
+	file := newFile()
+	before := &ExprStmt{Expr: SymbolExpr{Value: "before"}}
+	after1 := &ExprStmt{Expr: SymbolExpr{Value: "after1"}}
+	after2 := &ExprStmt{Expr: SymbolExpr{Value: "after2"}}
+
+	result := Apply(file, func(c *Cursor) bool {
+		switch n := c.Node().(type) {
+		case *ReturnStmt:
+			c.InsertBefore(before)
+			c.InsertAfter(after1)
+			c.InsertAfter(after2)
+		case *ExprStmt:
+			if n == before || n == after1 || n == after2 {
+				t.Error("an inserted node was walked")
+			}
+		}
+		return true
+	}, nil)
+
+	body := functionBody(result)
+	want := []Stmt{functionBody(file)[0], functionBody(file)[1], before, functionBody(file)[2], after1, after2}
+	if len(body) != len(want) {
+		t.Fatalf("got %d statements, want %d", len(body), len(want))
+	}
+	for i := range want {
+		if body[i] != want[i] {
+			t.Errorf("statement %d: got %T, want %T", i, body[i], want[i])
+		}
+	}
+
+	checkUnchanged(t, file)
+}
+
+func TestApplyReplaceInNestedBlock(t *testing.T) {
+	file := newFile()
+
+	result := Apply(file, nil, func(c *Cursor) bool {
+		if symbol, ok := c.Node().(SymbolExpr); ok && symbol.Value == "y" {
+			c.Replace(SymbolExpr{Span: symbol.Span, Value: "w"})
+		}
+		return true
+	})
+
+	block := functionBody(result)[1].(*BlockStmt)
+	if got := block.Body[0].(*ExprStmt).Expr.(SymbolExpr).Value; got != "w" {
+		t.Errorf("got %q, want \"w\"", got)
+	}
+	if block.Span != span(23, 29) {
+		t.Errorf("the span of the copied block changed to %v", block.Span)
+	}
+
+	checkUnchanged(t, file)
+}
+
+func TestApplyStop(t *testing.T) {
+	file := newFile()
+	stopped := false
+
+	result := Apply(file, func(c *Cursor) bool {
+		if stopped {
+			t.Errorf("pre was called for %T after post returned false", c.Node())
+		}
+		return true
+	}, func(c *Cursor) bool {
+		if stopped {
+			t.Errorf("post was called for %T after it returned false", c.Node())
+		}
+		if integer, ok := c.Node().(IntegerExpr); ok {
+			integer.Value = 2
+			c.Replace(integer)
+			stopped = true
+			return false
+		}
+		return true
+	})
+
+	right := functionBody(result)[0].(*ExprStmt).Expr.(BinaryExpr).Right.(IntegerExpr)
+	if right.Value != 2 {
+		t.Errorf("the change made before stopping was lost, got %d", right.Value)
+	}
+
+	checkUnchanged(t, file)
+}
+
+func TestApplyInvalidOperations(t *testing.T) {
+	tests := map[string]ApplyFunc{
+		"delete in a field": func(c *Cursor) bool {
+			if _, ok := c.Node().(IntegerExpr); ok {
+				c.Delete()
+			}
+			return true
+		},
+		"statement in an expression field": func(c *Cursor) bool {
+			if _, ok := c.Node().(IntegerExpr); ok {
+				c.Replace(&BreakStmt{})
+			}
+			return true
+		},
+	}
+
+	for name, pre := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Apply did not panic")
+				}
+			}()
+			Apply(newFile(), pre, nil)
+		})
+	}
+}
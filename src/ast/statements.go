@@ -1,5 +1,9 @@
 package ast
 
+import (
+	"github.com/ZiplEix/c_parser/src/lexer"
+)
+
 type VarType int
 
 // Enum for the different types of variables that can be declared.
@@ -11,72 +15,263 @@ const (
 	CHAR
 	SHORT
 	LONG
+	LONG_LONG
+	BIT_INT
+	LONG_DOUBLE
+	TYPE_NAME // a name declared by typedef, spelled in the TypeName field
 )
 
-// Enum for the different types of comments that can be declared.
-const (
-	SINGLE_LINE_COMMENT VarType = iota
-	MULTI_LINE_COMMENT
-)
+// Trivia holds the comments attached to a statement. Comments inside a nested
+// statement belong to that statement and are not repeated in its parents.
+type Trivia struct {
+	Leading  []lexer.Comment // comments on the lines above the statement
+	Inner    []lexer.Comment // comments between the first and the last token of the statement
+	Trailing []lexer.Comment // comments after the statement, on its last line
+}
+
+func (t *Trivia) SetTrivia(trivia Trivia) {
+	*t = trivia
+}
+
+// File is the root of the AST of a source file. Its Trivia.Inner holds the
+// comments after the last statement.
+type File struct {
+	Span
+	Trivia
+	Name string
+	Body []Stmt
+}
+
+// BadStmt is a placeholder for a statement containing a syntax error, it
+// covers the tokens skipped while recovering from it.
+type BadStmt struct {
+	Span
+	Trivia
+}
+
+func (b BadStmt) StmtNode() {}
+
+// EmptyStmt is the null statement, a lone ';'.
+type EmptyStmt struct {
+	Span
+	Trivia
+}
+
+func (e EmptyStmt) StmtNode() {}
 
 type BlockStmt struct {
+	Span
+	Trivia
 	Body []Stmt
 }
 
-func (b BlockStmt) stmt() {}
+func (b BlockStmt) StmtNode() {}
 
 type ExprStmt struct {
+	Span
+	Trivia
 	Expr Expr
 }
 
-func (e ExprStmt) stmt() {}
+func (e ExprStmt) StmtNode() {}
 
 type VarDeclarationStmt struct {
+	Span
+	Trivia
 	Name         string
 	IsConst      bool
 	IsSigned     bool
 	PointerLevel int
 	Type         VarType
+	TypeName     string
 	AssignedExpr Expr
 }
 
-func (v VarDeclarationStmt) stmt() {}
+func (v VarDeclarationStmt) StmtNode() {}
 
+// ReturnStmt is a return statement, Expr is nil in `return;`.
 type ReturnStmt struct {
+	Span
+	Trivia
 	Expr Expr
 }
 
-func (r ReturnStmt) stmt() {}
+func (r ReturnStmt) StmtNode() {}
 
-type CommentStmt struct {
-	Value string
-	Type  VarType
+// IncluderStmt is an #include line, Value is the header name with its
+// delimiters and Raw the whole line as written. When the preprocessor found
+// the header, Path is the file it resolved to and Decls the statements it
+// contains.
+type IncluderStmt struct {
+	Span
+	Trivia
+	Value  string
+	Raw    string
+	System bool // the header name is between angle brackets
+	Path   string
+	Decls  []Stmt
 }
 
-func (c CommentStmt) stmt() {}
+func (i IncluderStmt) StmtNode() {}
 
-type IncluderStmt struct {
-	Value string
+// DirectiveStmt is a preprocessing directive kept verbatim, Name is the
+// directive name (define, pragma, ifdef...) and Raw the whole line.
+type DirectiveStmt struct {
+	Span
+	Trivia
+	Name string
+	Raw  string
 }
 
-func (i IncluderStmt) stmt() {}
+func (d DirectiveStmt) StmtNode() {}
+
+// TypedefStmt declares Name as an alias of the type.
+type TypedefStmt struct {
+	Span
+	Trivia
+	Name         string
+	IsConst      bool
+	IsSigned     bool
+	PointerLevel int
+	Type         VarType
+	TypeName     string
+}
+
+func (t TypedefStmt) StmtNode() {}
 
 type Parameter struct {
+	Span
 	Name         string
 	IsConst      bool
 	IsSigned     bool
 	PointerLevel int
 	Type         VarType
+	TypeName     string
 }
 
+// FunctionDeclarationStmt is a function definition, or a prototype when Body
+// is nil.
 type FunctionDeclarationStmt struct {
+	Span
+	Trivia
 	Parameters   []Parameter
+	IsVariadic   bool
 	Name         string
 	Body         []Stmt
 	ReturnType   VarType
+	TypeName     string
 	IsConst      bool
 	IsSigned     bool
 	PointerLevel int
 }
 
-func (f FunctionDeclarationStmt) stmt() {}
+func (f FunctionDeclarationStmt) StmtNode() {}
+
+// IfStmt is an if statement, Else is nil when there is no else branch.
+type IfStmt struct {
+	Span
+	Trivia
+	Cond Expr
+	Then Stmt
+	Else Stmt
+}
+
+func (i IfStmt) StmtNode() {}
+
+type WhileStmt struct {
+	Span
+	Trivia
+	Cond Expr
+	Body Stmt
+}
+
+func (w WhileStmt) StmtNode() {}
+
+type DoWhileStmt struct {
+	Span
+	Trivia
+	Body Stmt
+	Cond Expr
+}
+
+func (d DoWhileStmt) StmtNode() {}
+
+// ForStmt is a for loop. Init is an *ExprStmt or a *VarDeclarationStmt whose
+// scope is the loop, Init, Cond and Post are nil when omitted.
+type ForStmt struct {
+	Span
+	Trivia
+	Init Stmt
+	Cond Expr
+	Post Expr
+	Body Stmt
+}
+
+func (f ForStmt) StmtNode() {}
+
+type BreakStmt struct {
+	Span
+	Trivia
+}
+
+func (b BreakStmt) StmtNode() {}
+
+type ContinueStmt struct {
+	Span
+	Trivia
+}
+
+func (c ContinueStmt) StmtNode() {}
+
+// SwitchStmt is a switch statement. Its Body holds a *CaseClause for each
+// group of labels, preceded by the statements written before the first label.
+type SwitchStmt struct {
+	Span
+	Trivia
+	Tag  Expr
+	Body []Stmt
+}
+
+func (s SwitchStmt) StmtNode() {}
+
+// CaseLabel is a `case` label, or a `default` one when Value is nil. High is
+// the end of the GNU case range `case Value ... High:`, and nil otherwise.
+type CaseLabel struct {
+	Span
+	Value Expr
+	High  Expr
+}
+
+// CaseClause holds the consecutive labels of a switch and the statements
+// following them until the next label. Fallthrough reports whether the end of
+// Body is reached and runs into the next clause, that is when the last
+// statement is not a break, continue, return or goto, possibly at the end of a
+// block or after a label. Other statements are not analysed, so an if whose
+// branches all jump still falls through. It is false for the last clause.
+type CaseClause struct {
+	Span
+	Trivia
+	Labels      []CaseLabel
+	Body        []Stmt
+	Fallthrough bool
+}
+
+func (c CaseClause) StmtNode() {}
+
+// LabeledStmt is a statement preceded by a label, the target of a goto.
+type LabeledStmt struct {
+	Span
+	Trivia
+	Label string
+	Stmt  Stmt
+}
+
+func (l LabeledStmt) StmtNode() {}
+
+type GotoStmt struct {
+	Span
+	Trivia
+	Label string
+}
+
+func (g GotoStmt) StmtNode() {}
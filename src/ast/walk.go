@@ -0,0 +1,180 @@
+package ast
+
+// A Visitor's Visit method is invoked for each node encountered by Walk. If
+// the result visitor w is not nil, Walk visits each of the children of node
+// with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Composite is implemented by the nodes defined outside this package that
+// have children, Walk visits the nodes returned by Children in order.
+type Composite interface {
+	Node
+	Children() []Node
+}
+
+// Walk traverses an AST in depth-first order: it starts by calling
+// v.Visit(node); node must not be nil. If the visitor w returned by
+// v.Visit(node) is not nil, Walk is invoked recursively with visitor w for
+// each of the non-nil children of node, followed by a call of w.Visit(nil).
+//
+// The parameters of a FunctionDeclarationStmt are visited as *Parameter and
+// the labels of a CaseClause as *CaseLabel. Nodes of other packages are
+// walked through the Composite interface, the ones not implementing it having
+// no children.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	// Expressions
+	case BadExpr, IntegerExpr, UnsignedIntegerExpr, FloatExpr, CharacterExpr, StringExpr, SymbolExpr:
+		// nothing to do
+
+	case BinaryExpr:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+
+	case ParenExpr:
+		Walk(v, n.X)
+
+	case UnaryExpr:
+		Walk(v, n.Operand)
+
+	case PostfixExpr:
+		Walk(v, n.Operand)
+
+	case AssignmentExpr:
+		Walk(v, n.Assignee)
+		Walk(v, n.Value)
+
+	case CallExpr:
+		Walk(v, n.Func)
+		for _, arg := range n.Args {
+			Walk(v, arg)
+		}
+
+	case MemberExpr:
+		Walk(v, n.X)
+
+	case IndexExpr:
+		Walk(v, n.X)
+		Walk(v, n.Index)
+
+	// Statements
+	case *File:
+		walkStmtList(v, n.Body)
+
+	case *BadStmt, *EmptyStmt, *DirectiveStmt, *TypedefStmt, *Parameter, *BreakStmt, *ContinueStmt, *GotoStmt:
+		// nothing to do
+
+	case *BlockStmt:
+		walkStmtList(v, n.Body)
+
+	case *ExprStmt:
+		Walk(v, n.Expr)
+
+	case *VarDeclarationStmt:
+		if n.AssignedExpr != nil {
+			Walk(v, n.AssignedExpr)
+		}
+
+	case *ReturnStmt:
+		if n.Expr != nil {
+			Walk(v, n.Expr)
+		}
+
+	case *IfStmt:
+		Walk(v, n.Cond)
+		Walk(v, n.Then)
+		if n.Else != nil {
+			Walk(v, n.Else)
+		}
+
+	case *WhileStmt:
+		Walk(v, n.Cond)
+		Walk(v, n.Body)
+
+	case *DoWhileStmt:
+		Walk(v, n.Body)
+		Walk(v, n.Cond)
+
+	case *ForStmt:
+		if n.Init != nil {
+			Walk(v, n.Init)
+		}
+		if n.Cond != nil {
+			Walk(v, n.Cond)
+		}
+		if n.Post != nil {
+			Walk(v, n.Post)
+		}
+		Walk(v, n.Body)
+
+	case *SwitchStmt:
+		Walk(v, n.Tag)
+		walkStmtList(v, n.Body)
+
+	case *CaseClause:
+		for i := range n.Labels {
+			Walk(v, &n.Labels[i])
+		}
+		walkStmtList(v, n.Body)
+
+	case *CaseLabel:
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+		if n.High != nil {
+			Walk(v, n.High)
+		}
+
+	case *LabeledStmt:
+		Walk(v, n.Stmt)
+
+	case *IncluderStmt:
+		walkStmtList(v, n.Decls)
+
+	case *FunctionDeclarationStmt:
+		for i := range n.Parameters {
+			Walk(v, &n.Parameters[i])
+		}
+		walkStmtList(v, n.Body)
+
+	case Composite:
+		for _, child := range n.Children() {
+			if child != nil {
+				Walk(v, child)
+			}
+		}
+	}
+
+	v.Visit(nil)
+}
+
+func walkStmtList(v Visitor, list []Stmt) {
+	for _, stmt := range list {
+		if stmt != nil {
+			Walk(v, stmt)
+		}
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order: it starts by calling
+// f(node); node must not be nil. If f returns true, Inspect invokes f
+// recursively for each of the non-nil children of node, followed by a call
+// of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
@@ -0,0 +1,275 @@
+// Package checker runs the semantic checks on the AST built by the parser.
+package checker
+
+import (
+	"strings"
+
+	"github.com/ZiplEix/c_parser/src/ast"
+	"github.com/ZiplEix/c_parser/src/diagnostic"
+	"github.com/ZiplEix/c_parser/src/lexer"
+	"github.com/ZiplEix/c_parser/src/libc"
+)
+
+type SymbolKind int
+
+// Enum for the different kinds of names a scope can hold.
+const (
+	VARIABLE SymbolKind = iota
+	FUNCTION
+	TYPE
+	MACRO
+)
+
+// Symbol is a declared name. Header is the standard header it comes from
+// when it was declared by the libc database.
+type Symbol struct {
+	Name   string
+	Kind   SymbolKind
+	Header string
+}
+
+type scope struct {
+	parent  *scope
+	symbols map[string]*Symbol
+}
+
+func (s *scope) lookup(name string) (*Symbol, bool) {
+	for ; s != nil; s = s.parent {
+		if symbol, ok := s.symbols[name]; ok {
+			return symbol, true
+		}
+	}
+	return nil, false
+}
+
+type checker struct {
+	scope       *scope
+	diagnostics []diagnostic.Diagnostic
+	// an included header is neither loaded nor known by the libc database,
+	// the names it declares are unknown so undeclared names are not reported
+	incomplete bool
+}
+
+// Check resolves the names used in a parsed file and returns the semantic
+// errors found. Standard headers that the preprocessor did not load are
+// resolved with the libc database. The case values of the switches and the
+// labels targeted by gotos are checked too.
+func Check(file *ast.File) []diagnostic.Diagnostic {
+	c := &checker{}
+	c.openScope()
+	c.checkStmts(file.Body)
+
+	return c.diagnostics
+}
+
+func (c *checker) openScope() {
+	c.scope = &scope{parent: c.scope, symbols: map[string]*Symbol{}}
+}
+
+func (c *checker) closeScope() {
+	c.scope = c.scope.parent
+}
+
+func (c *checker) declare(name string, kind SymbolKind, header string) {
+	c.scope.symbols[name] = &Symbol{Name: name, Kind: kind, Header: header}
+}
+
+func (c *checker) checkStmts(stmts []ast.Stmt) {
+	for _, stmt := range stmts {
+		c.checkStmt(stmt)
+	}
+}
+
+func (c *checker) checkStmt(stmt ast.Stmt) {
+	switch s := stmt.(type) {
+	case *ast.BlockStmt:
+		c.openScope()
+		c.checkStmts(s.Body)
+		c.closeScope()
+	case *ast.ExprStmt:
+		c.checkExpr(s.Expr)
+	case *ast.VarDeclarationStmt:
+		// the scope of a variable starts before its initializer
+		c.declare(s.Name, VARIABLE, "")
+		if s.AssignedExpr != nil {
+			c.checkExpr(s.AssignedExpr)
+		}
+	case *ast.FunctionDeclarationStmt:
+		c.declare(s.Name, FUNCTION, "")
+		if s.Body != nil {
+			c.openScope()
+			for _, param := range s.Parameters {
+				if param.Name != "" {
+					c.declare(param.Name, VARIABLE, "")
+				}
+			}
+			c.checkStmts(s.Body)
+			c.closeScope()
+			c.checkLabels(s.Body)
+		}
+	case *ast.ReturnStmt:
+		if s.Expr != nil {
+			c.checkExpr(s.Expr)
+		}
+	case *ast.IfStmt:
+		c.checkExpr(s.Cond)
+		c.checkStmt(s.Then)
+		if s.Else != nil {
+			c.checkStmt(s.Else)
+		}
+	case *ast.WhileStmt:
+		c.checkExpr(s.Cond)
+		c.checkStmt(s.Body)
+	case *ast.DoWhileStmt:
+		c.checkStmt(s.Body)
+		c.checkExpr(s.Cond)
+	case *ast.ForStmt:
+		// a variable declared in the init clause is local to the loop
+		c.openScope()
+		if s.Init != nil {
+			c.checkStmt(s.Init)
+		}
+		if s.Cond != nil {
+			c.checkExpr(s.Cond)
+		}
+		if s.Post != nil {
+			c.checkExpr(s.Post)
+		}
+		c.checkStmt(s.Body)
+		c.closeScope()
+	case *ast.SwitchStmt:
+		c.checkSwitch(s)
+	case *ast.CaseClause:
+		for _, label := range s.Labels {
+			if label.Value != nil {
+				c.checkExpr(label.Value)
+			}
+			if label.High != nil {
+				c.checkExpr(label.High)
+			}
+		}
+		c.checkStmts(s.Body)
+	case *ast.LabeledStmt:
+		c.checkStmt(s.Stmt)
+	case *ast.TypedefStmt:
+		c.declare(s.Name, TYPE, "")
+	case *ast.IncluderStmt:
+		c.include(s)
+	case *ast.DirectiveStmt:
+		if s.Name == "define" {
+			c.defineDirective(s)
+		}
+	}
+}
+
+// include declares the names of an included header: its statements when the
+// preprocessor loaded it, or the entries of the libc database.
+func (c *checker) include(s *ast.IncluderStmt) {
+	if s.Decls != nil {
+		c.checkStmts(s.Decls)
+		return
+	}
+
+	name := strings.Trim(s.Value, "<>\"")
+	header, ok := libc.Lookup(name)
+	if !ok || !s.System {
+		c.incomplete = true
+		return
+	}
+
+	for _, typedef := range header.Types {
+		c.declare(typedef.Name, TYPE, name)
+	}
+	for _, variable := range header.Variables {
+		c.declare(variable.Name, VARIABLE, name)
+	}
+	for _, macro := range header.Macros {
+		c.declare(macro.Name, MACRO, name)
+	}
+	for _, function := range header.Functions {
+		c.declare(function.Name, FUNCTION, name)
+	}
+}
+
+// defineDirective declares the macro of a #define kept verbatim by the
+// preprocessor pass-through mode.
+func (c *checker) defineDirective(s *ast.DirectiveStmt) {
+	rest := strings.TrimSpace(s.Raw[strings.Index(s.Raw, "define")+len("define"):])
+
+	end := strings.IndexFunc(rest, func(r rune) bool {
+		return !(r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'))
+	})
+	if end < 0 {
+		end = len(rest)
+	}
+
+	if end > 0 {
+		c.declare(rest[:end], MACRO, "")
+	}
+}
+
+func (c *checker) checkExpr(expr ast.Expr) {
+	switch e := expr.(type) {
+	case ast.SymbolExpr:
+		if _, ok := c.scope.lookup(e.Value); !ok && !c.incomplete {
+			span := diagnostic.Span{Start: e.Pos(), End: e.End()}
+			c.diagnostics = append(c.diagnostics, diagnostic.Errorf(diagnostic.CHECK_UNDECLARED, span, "use of undeclared identifier '%s'", e.Value))
+		}
+	case ast.BinaryExpr:
+		c.checkExpr(e.Left)
+		c.checkExpr(e.Right)
+	case ast.ParenExpr:
+		c.checkExpr(e.X)
+	case ast.UnaryExpr:
+		c.checkExpr(e.Operand)
+		if e.Operator.Kind == lexer.INCREMENT || e.Operator.Kind == lexer.DECREMENT {
+			c.checkAssignable(e.Operand)
+		}
+	case ast.PostfixExpr:
+		c.checkExpr(e.Operand)
+		c.checkAssignable(e.Operand)
+	case ast.AssignmentExpr:
+		c.checkExpr(e.Assignee)
+		c.checkAssignable(e.Assignee)
+		c.checkExpr(e.Value)
+	case ast.CallExpr:
+		c.checkExpr(e.Func)
+		for _, arg := range e.Args {
+			c.checkExpr(arg)
+		}
+	case ast.MemberExpr:
+		c.checkExpr(e.X)
+	case ast.IndexExpr:
+		c.checkExpr(e.X)
+		c.checkExpr(e.Index)
+	}
+}
+
+// checkAssignable reports an expression modified by an assignment or an
+// increment that does not designate an object.
+func (c *checker) checkAssignable(expr ast.Expr) {
+	if c.isLvalue(expr) {
+		return
+	}
+
+	span := diagnostic.Span{Start: expr.Pos(), End: expr.End()}
+	c.diagnostics = append(c.diagnostics, diagnostic.Errorf(diagnostic.CHECK_NOT_ASSIGNABLE, span, "expression is not assignable"))
+}
+
+// isLvalue reports whether the expression designates an object. Names that
+// are not declared, or are macros, are given the benefit of the doubt.
+func (c *checker) isLvalue(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case ast.SymbolExpr:
+		symbol, ok := c.scope.lookup(e.Value)
+		return !ok || symbol.Kind == VARIABLE || symbol.Kind == MACRO
+	case ast.ParenExpr:
+		return c.isLvalue(e.X)
+	case ast.UnaryExpr:
+		return e.Operator.Kind == lexer.STAR
+	case ast.MemberExpr, ast.IndexExpr, ast.BadExpr:
+		return true
+	}
+
+	return false
+}
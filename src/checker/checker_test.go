@@ -0,0 +1,151 @@
+package checker
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ZiplEix/c_parser/src/ast"
+	"github.com/ZiplEix/c_parser/src/diagnostic"
+	"github.com/ZiplEix/c_parser/src/lexer"
+	"github.com/ZiplEix/c_parser/src/parser"
+	"github.com/ZiplEix/c_parser/src/preprocessor"
+)
+
+// check preprocesses, parses and checks a source, and returns the codes of
+// the diagnostics of the checker. Syntax errors fail the test.
+func check(t *testing.T, source string) []string {
+	t.Helper()
+
+	tokens, diagnostics := lexer.Tokensize(source)
+	tokens, ppDiagnostics := preprocessor.Preprocess(tokens, preprocessor.Config{Source: source})
+	block, parseDiagnostics := parser.Parse(tokens)
+
+	for _, d := range append(append(diagnostics, ppDiagnostics...), parseDiagnostics...) {
+		if d.Severity == diagnostic.ERROR {
+			t.Fatalf("unexpected error: %v", d)
+		}
+	}
+
+	codes := []string{}
+	for _, d := range Check(&ast.File{Span: block.Span, Body: block.Body}) {
+		codes = append(codes, d.Code)
+	}
+	return codes
+}
+
+func TestCheckAssignable(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{"variable", "int x; x = 1;", nil},
+		{"chained", "int a; int b; int c; a = b = c;", nil},
+		{"member", "int p; int x; p.x = x; p->x = x;", nil},
+		{"subscript", "int a; int i; a[i] = 0; a[i]++; --a[i];", nil},
+		{"dereference", "int p; *p = 1; (*p)++;", nil},
+		{"compound", "int x; x += 2; x <<= 1;", nil},
+		{"integer", "int x; 1 = x;", []string{diagnostic.CHECK_NOT_ASSIGNABLE}},
+		{"binary", "int x; x + 1 = 2;", []string{diagnostic.CHECK_NOT_ASSIGNABLE}},
+		{"call", "int f(void); int x; f() = x;", []string{diagnostic.CHECK_NOT_ASSIGNABLE}},
+		{"function", "int f(void); f++;", []string{diagnostic.CHECK_NOT_ASSIGNABLE}},
+		{"address", "int x; &x = 0;", []string{diagnostic.CHECK_NOT_ASSIGNABLE}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := check(t, test.source); !slices.Equal(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckUndeclared(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{"declared", "int x; int y = x;", nil},
+		{"undeclared", "int y = x;", []string{diagnostic.CHECK_UNDECLARED}},
+		{"own initializer", "int x = x;", nil},
+		{"parameter", "int f(int n) { return n; }", nil},
+		{"recursion", "int f(int n) { return f(n - 1); }", nil},
+		{"block scope", "void f(void) { { int x; } x = 1; }", []string{diagnostic.CHECK_UNDECLARED}},
+		{"for scope", "void f(void) { for (int i = 0; i < 1; i++) ; i = 1; }", []string{diagnostic.CHECK_UNDECLARED}},
+		{"typedef", "typedef int T; int f(T x) { return x; }", nil},
+		{
+			"libc function and macro",
+			"#include <stdio.h>\nint main(void) { printf(\"%d\", 1); return EOF; }",
+			nil,
+		},
+		{"libc variable", "#include <stdio.h>\nvoid f(void) { fflush(stdout); }", nil},
+		{"libc type", "#include <stdio.h>\nvoid f(void) { FILE *file = fopen(\"a\", \"r\"); fclose(file); }", nil},
+		{"other header", "#include <string.h>\nint f(void) { return strlen(NULL); }", nil},
+		{"wrong header", "#include <stdio.h>\nint f(void) { return strlen(\"a\"); }", []string{diagnostic.CHECK_UNDECLARED}},
+		{"no header", "int f(void) { return strlen(\"a\"); }", []string{diagnostic.CHECK_UNDECLARED}},
+		{"unknown system header", "#include <unknown.h>\nint f(void) { return g(); }", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := check(t, test.source); !slices.Equal(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckSwitch(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{"distinct", "case 1: case 2: case 'a': case -1: ;", nil},
+		{"duplicate", "case 1: break; case 1: ;", []string{diagnostic.CHECK_DUPLICATE_CASE}},
+		{"same value", "case 'a': case 97: ;", []string{diagnostic.CHECK_DUPLICATE_CASE}},
+		{"constant expression", "case 1 << 2: case 2 * 2: ;", []string{diagnostic.CHECK_DUPLICATE_CASE}},
+		{"overlapping ranges", "case 1 ... 5: case 5 ... 9: ;", []string{diagnostic.CHECK_DUPLICATE_CASE}},
+		{"value in range", "case 1 ... 5: case 3: ;", []string{diagnostic.CHECK_DUPLICATE_CASE}},
+		{"adjacent ranges", "case 1 ... 5: case 6 ... 9: ;", nil},
+		{"empty range", "case 5 ... 1: case 3: ;", nil},
+		{"defaults", "default: break; default: ;", []string{diagnostic.CHECK_DUPLICATE_CASE}},
+		{"nested switch", "case 1: switch (x) { case 1: ; } ;", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			source := "void f(int x) { switch (x) { " + test.source + " } }"
+			if got := check(t, source); !slices.Equal(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckLabels(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{"backward", "void f(void) { again: goto again; }", nil},
+		{"forward", "void f(void) { goto end; end: ; }", nil},
+		{"nested", "void f(void) { { goto inner; } { inner: ; } }", nil},
+		{"undefined", "void f(void) { goto end; }", []string{diagnostic.CHECK_UNDEFINED_LABEL}},
+		{"other function", "void f(void) { end: ; } void g(void) { goto end; }", []string{diagnostic.CHECK_UNDEFINED_LABEL}},
+		{"duplicate", "void f(void) { a: ; { a: ; } }", []string{diagnostic.CHECK_DUPLICATE_LABEL}},
+		{"same name in two functions", "void f(void) { a: ; } void g(void) { a: ; }", nil},
+		{"label and variable", "void f(void) { int a; a: a = 1; goto a; }", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := check(t, test.source); !slices.Equal(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
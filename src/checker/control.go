@@ -0,0 +1,201 @@
+package checker
+
+import (
+	"fmt"
+
+	"github.com/ZiplEix/c_parser/src/ast"
+	"github.com/ZiplEix/c_parser/src/diagnostic"
+	"github.com/ZiplEix/c_parser/src/lexer"
+)
+
+func nodeSpan(node ast.Node) diagnostic.Span {
+	return diagnostic.Span{Start: node.Pos(), End: node.End()}
+}
+
+// caseRange is the values matched by a case label, a single value having
+// low == high.
+type caseRange struct {
+	low, high int64
+	label     *ast.CaseLabel
+}
+
+func (r caseRange) String() string {
+	if r.low == r.high {
+		return fmt.Sprint(r.low)
+	}
+	return fmt.Sprintf("%d ... %d", r.low, r.high)
+}
+
+// checkSwitch checks the switch and reports the values matched by several
+// labels of its clauses. Labels of nested switches are checked with them.
+func (c *checker) checkSwitch(s *ast.SwitchStmt) {
+	c.checkExpr(s.Tag)
+
+	var ranges []caseRange
+	var defaultLabel *ast.CaseLabel
+
+	for _, stmt := range s.Body {
+		clause, ok := stmt.(*ast.CaseClause)
+		if !ok {
+			continue
+		}
+
+		for i := range clause.Labels {
+			label := &clause.Labels[i]
+
+			if label.Value == nil {
+				if defaultLabel != nil {
+					c.diagnostics = append(c.diagnostics, diagnostic.Errorf(diagnostic.CHECK_DUPLICATE_CASE, nodeSpan(label), "multiple default labels in one switch").
+						WithNote(nodeSpan(defaultLabel), "previous default label is here"))
+				}
+				defaultLabel = label
+				continue
+			}
+
+			current, ok := labelRange(label)
+			if !ok {
+				continue
+			}
+
+			for _, previous := range ranges {
+				if current.low <= previous.high && previous.low <= current.high {
+					c.diagnostics = append(c.diagnostics, diagnostic.Errorf(diagnostic.CHECK_DUPLICATE_CASE, nodeSpan(label), "duplicate case value %s", current).
+						WithNote(nodeSpan(previous.label), "previous case %s is here", previous))
+					break
+				}
+			}
+			ranges = append(ranges, current)
+		}
+	}
+
+	c.openScope()
+	c.checkStmts(s.Body)
+	c.closeScope()
+}
+
+// labelRange returns the values matched by a case label, when they are known.
+func labelRange(label *ast.CaseLabel) (caseRange, bool) {
+	low, ok := constValue(label.Value)
+	if !ok {
+		return caseRange{}, false
+	}
+
+	high := low
+	if label.High != nil {
+		if high, ok = constValue(label.High); !ok || high < low {
+			// an empty range matches nothing
+			return caseRange{}, false
+		}
+	}
+
+	return caseRange{low: low, high: high, label: label}, true
+}
+
+// constValue computes the value of an integer constant expression made of
+// literals and arithmetic operators.
+func constValue(expr ast.Expr) (int64, bool) {
+	switch e := expr.(type) {
+	case ast.IntegerExpr:
+		return e.Value, true
+	case ast.UnsignedIntegerExpr:
+		return int64(e.Value), true
+	case ast.CharacterExpr:
+		return e.Value, true
+	case ast.ParenExpr:
+		return constValue(e.X)
+	case ast.UnaryExpr:
+		operand, ok := constValue(e.Operand)
+		if !ok {
+			return 0, false
+		}
+
+		switch e.Operator.Kind {
+		case lexer.MINUS:
+			return -operand, true
+		case lexer.PLUS:
+			return operand, true
+		case lexer.TILDE:
+			return ^operand, true
+		case lexer.LOGICAL_NOT:
+			if operand == 0 {
+				return 1, true
+			}
+			return 0, true
+		}
+	case ast.BinaryExpr:
+		left, ok := constValue(e.Left)
+		if !ok {
+			return 0, false
+		}
+		right, ok := constValue(e.Right)
+		if !ok {
+			return 0, false
+		}
+
+		switch e.Operator.Kind {
+		case lexer.PLUS:
+			return left + right, true
+		case lexer.MINUS:
+			return left - right, true
+		case lexer.STAR:
+			return left * right, true
+		case lexer.SLASH:
+			if right != 0 {
+				return left / right, true
+			}
+		case lexer.PERCENT:
+			if right != 0 {
+				return left % right, true
+			}
+		case lexer.ESPERLUETTE:
+			return left & right, true
+		case lexer.PIPE:
+			return left | right, true
+		case lexer.CARET:
+			return left ^ right, true
+		case lexer.SHIFT_LEFT:
+			if right >= 0 && right < 64 {
+				return left << right, true
+			}
+		case lexer.SHIFT_RIGHT:
+			if right >= 0 && right < 64 {
+				return left >> right, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// checkLabels reports the labels defined twice in a function body, and the
+// gotos jumping to a label it does not define.
+func (c *checker) checkLabels(body []ast.Stmt) {
+	labels := map[string]*ast.LabeledStmt{}
+	gotos := []*ast.GotoStmt{}
+
+	for _, stmt := range body {
+		ast.Inspect(stmt, func(node ast.Node) bool {
+			switch n := node.(type) {
+			case *ast.LabeledStmt:
+				if previous, ok := labels[n.Label]; ok {
+					span := diagnostic.Span{Start: n.Pos(), End: n.Stmt.Pos()}
+					c.diagnostics = append(c.diagnostics, diagnostic.Errorf(diagnostic.CHECK_DUPLICATE_LABEL, span, "redefinition of label '%s'", n.Label).
+						WithNote(diagnostic.Span{Start: previous.Pos(), End: previous.Stmt.Pos()}, "previous definition is here"))
+				} else {
+					labels[n.Label] = n
+				}
+			case *ast.GotoStmt:
+				gotos = append(gotos, n)
+			case ast.Expr:
+				return false
+			}
+			return true
+		})
+	}
+
+	for _, jump := range gotos {
+		if _, ok := labels[jump.Label]; !ok {
+			c.diagnostics = append(c.diagnostics, diagnostic.Errorf(diagnostic.CHECK_UNDEFINED_LABEL, nodeSpan(jump), "use of undeclared label '%s'", jump.Label))
+		}
+	}
+}
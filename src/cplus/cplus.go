@@ -0,0 +1,198 @@
+// Package cplus is the entry point of the C+ front end: it chains the lexer,
+// the preprocessor, the parser and the semantic checker.
+//
+//	file, diagnostics := cplus.ParseFile("main.c", src, cplus.Options{})
+//	for _, d := range diagnostics {
+//		fmt.Fprintln(os.Stderr, d.Error())
+//	}
+package cplus
+
+import (
+	"io"
+	"slices"
+
+	"github.com/ZiplEix/c_parser/src/ast"
+	"github.com/ZiplEix/c_parser/src/checker"
+	"github.com/ZiplEix/c_parser/src/diagnostic"
+	"github.com/ZiplEix/c_parser/src/lexer"
+	"github.com/ZiplEix/c_parser/src/parser"
+	"github.com/ZiplEix/c_parser/src/preprocessor"
+)
+
+// Diagnostic is an error, warning or note reported by one of the stages.
+type Diagnostic = diagnostic.Diagnostic
+
+type Dialect int
+
+// Enum for the language revisions the front end accepts. The constructs
+// introduced by C23 (binary constants, digit separators, the wb suffix,
+// #elifdef, #elifndef and __VA_OPT__) are reported as errors in C11 and C17.
+const (
+	// CPLUS is C17 with the C+ extensions, it defines __CPLUS__ and accepts
+	// the C23 constructs as extensions.
+	CPLUS Dialect = iota
+	C11
+	C17
+	C23
+)
+
+// String returns the name of the dialect, as given to -std.
+func (d Dialect) String() string {
+	switch d {
+	case CPLUS:
+		return "cplus"
+	case C11:
+		return "c11"
+	case C17:
+		return "c17"
+	case C23:
+		return "c23"
+	default:
+		return "unknown"
+	}
+}
+
+// preC23 reports whether the dialect rejects the constructs introduced by C23.
+func (d Dialect) preC23() bool {
+	return d == C11 || d == C17
+}
+
+// lexerOptions returns the options of the lexer in the dialect.
+func (d Dialect) lexerOptions() lexer.Options {
+	return lexer.Options{PreC23: d.preC23()}
+}
+
+// version returns the value of __STDC_VERSION__ in the dialect.
+func (d Dialect) version() string {
+	switch d {
+	case C11:
+		return "201112L"
+	case C23:
+		return "202311L"
+	default:
+		return "201710L"
+	}
+}
+
+// Options configures the front end. The zero value parses C+ with full
+// preprocessing, without comments in the AST.
+type Options struct {
+	Dialect Dialect
+
+	// KeepTrivia attaches the comments to the statements of the AST.
+	KeepTrivia bool
+
+	// Preprocessor selects whether directives are executed (EXPAND) or kept
+	// verbatim in the AST (PASS_THROUGH).
+	Preprocessor preprocessor.Mode
+	Defines      map[string]string // macros defined before the first line, like -DNAME=VALUE
+	IncludePaths []string          // directories searched for headers, like -I
+	SystemPaths  []string          // directories searched after IncludePaths, like -isystem
+
+	// SkipCheck only builds the AST, without running the semantic checker.
+	SkipCheck bool
+}
+
+// preprocessorConfig returns the configuration of the preprocessor for a
+// file, src being empty when the file is streamed.
+func preprocessorConfig(name, src string, opts Options) preprocessor.Config {
+	defines := map[string]string{}
+	if opts.Dialect == CPLUS {
+		defines["__CPLUS__"] = "1"
+	}
+	for name, value := range opts.Defines {
+		defines[name] = value
+	}
+
+	return preprocessor.Config{
+		File:         name,
+		Source:       src,
+		Defines:      defines,
+		Mode:         opts.Preprocessor,
+		Version:      opts.Dialect.version(),
+		PreC23:       opts.Dialect.preC23(),
+		IncludePaths: opts.IncludePaths,
+		SystemPaths:  opts.SystemPaths,
+	}
+}
+
+// withoutTrivia removes the comments from the tokens of a source.
+type withoutTrivia struct {
+	source lexer.TokenSource
+}
+
+func (w withoutTrivia) Next() lexer.Token {
+	token := w.source.Next()
+	token.Leading, token.Trailing = nil, nil
+	return token
+}
+
+// Tokenize returns the tokens of a file after preprocessing.
+func Tokenize(name string, src []byte, opts Options) ([]lexer.Token, []Diagnostic) {
+	tokens, diagnostics := lexer.TokensizeOptions(name, string(src), opts.Dialect.lexerOptions())
+
+	tokens, ppDiagnostics := preprocessor.Preprocess(tokens, preprocessorConfig(name, string(src), opts))
+	diagnostics = append(diagnostics, ppDiagnostics...)
+
+	if !opts.KeepTrivia {
+		for i := range tokens {
+			tokens[i].Leading, tokens[i].Trailing = nil, nil
+		}
+	}
+
+	return tokens, diagnostics
+}
+
+// ParseFile parses the source of a file, name being used in the positions
+// and by __FILE__. The AST is returned even when there are errors, the
+// statements that could not be parsed being ast.BadStmt.
+func ParseFile(name string, src []byte, opts Options) (*ast.File, []Diagnostic) {
+	tokens, diagnostics := Tokenize(name, src, opts)
+
+	block, parseDiagnostics := parser.Parse(tokens)
+	diagnostics = append(diagnostics, parseDiagnostics...)
+
+	file := &ast.File{
+		Span:   block.Span,
+		Trivia: block.Trivia,
+		Name:   name,
+		Body:   block.Body,
+	}
+
+	if !opts.SkipCheck {
+		diagnostics = append(diagnostics, checker.Check(file)...)
+	}
+
+	return file, diagnostics
+}
+
+// ParseReader is like ParseFile, the source being read from r while it is
+// parsed instead of being loaded at once. In the PASS_THROUGH mode, the text
+// of the directives is rebuilt from their tokens. The error is the one
+// returned by r, if it is not io.EOF.
+func ParseReader(name string, r io.Reader, opts Options) (*ast.File, []Diagnostic, error) {
+	scanner := lexer.NewScannerOptions(name, r, opts.Dialect.lexerOptions())
+	pp := preprocessor.New(scanner, preprocessorConfig(name, "", opts))
+
+	var source lexer.TokenSource = pp
+	if !opts.KeepTrivia {
+		source = withoutTrivia{source: pp}
+	}
+
+	block, parseDiagnostics := parser.ParseSource(source)
+
+	diagnostics := slices.Concat(scanner.Diagnostics(), pp.Diagnostics(), parseDiagnostics)
+
+	file := &ast.File{
+		Span:   block.Span,
+		Trivia: block.Trivia,
+		Name:   name,
+		Body:   block.Body,
+	}
+
+	if !opts.SkipCheck {
+		diagnostics = append(diagnostics, checker.Check(file)...)
+	}
+
+	return file, diagnostics, scanner.Err()
+}
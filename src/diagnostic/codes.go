@@ -0,0 +1,46 @@
+package diagnostic
+
+// Lexer diagnostic codes.
+const (
+	LEX_UNRECOGNIZED_TOKEN = "L0001"
+	LEX_UNTERMINATED       = "L0002"
+	LEX_INVALID_NUMBER     = "L0003"
+	LEX_INVALID_ESCAPE     = "L0004"
+	LEX_MULTI_CHARACTER    = "L0005"
+	LEX_C23_FEATURE        = "L0006"
+)
+
+// Parser diagnostic codes.
+const (
+	PARSE_UNEXPECTED_TOKEN = "P0001"
+	PARSE_EXPECTED_EXPR    = "P0002"
+	PARSE_UNEXPECTED_NODE  = "P0003"
+	PARSE_INTEGER_OVERFLOW = "P0004"
+	PARSE_FLOAT_RANGE      = "P0005"
+	PARSE_STRING_CONCAT    = "P0006"
+)
+
+// Preprocessor diagnostic codes.
+const (
+	PP_INVALID_DIRECTIVE  = "D0001"
+	PP_INVALID_MACRO      = "D0002"
+	PP_MACRO_REDEFINED    = "D0003"
+	PP_MACRO_ARGUMENTS    = "D0004"
+	PP_INVALID_PASTE      = "D0005"
+	PP_UNBALANCED_COND    = "D0006"
+	PP_INVALID_EXPRESSION = "D0007"
+	PP_USER_ERROR         = "D0008"
+	PP_USER_WARNING       = "D0009"
+	PP_EXTRA_TOKENS       = "D0010"
+	PP_INCLUDE_NOT_FOUND  = "D0011"
+	PP_C23_FEATURE        = "D0012"
+)
+
+// Semantic checker diagnostic codes.
+const (
+	CHECK_UNDECLARED      = "C0001"
+	CHECK_DUPLICATE_CASE  = "C0002"
+	CHECK_UNDEFINED_LABEL = "C0003"
+	CHECK_DUPLICATE_LABEL = "C0004"
+	CHECK_NOT_ASSIGNABLE  = "C0005"
+)
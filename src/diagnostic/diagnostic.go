@@ -0,0 +1,120 @@
+package diagnostic
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ZiplEix/c_parser/src/source"
+)
+
+type Severity int
+
+// Enum for the severity of a diagnostic.
+const (
+	ERROR Severity = iota
+	WARNING
+	NOTE
+)
+
+func (s Severity) String() string {
+	switch s {
+	case ERROR:
+		return "error"
+	case WARNING:
+		return "warning"
+	case NOTE:
+		return "note"
+	default:
+		return "unknown"
+	}
+}
+
+// Span is the source range a diagnostic points at, End is exclusive.
+type Span struct {
+	Start source.Position
+	End   source.Position
+}
+
+// Note is additional information attached to a diagnostic, optionally
+// pointing at another location.
+type Note struct {
+	Message string
+	Span    Span
+}
+
+// FixIt is a suggested edit: the text covered by Span should be replaced by
+// Replacement. An empty span (Start == End) is an insertion.
+type FixIt struct {
+	Span        Span
+	Replacement string
+}
+
+type Diagnostic struct {
+	Severity Severity
+	Code     string
+	Message  string
+	Span     Span
+	Notes    []Note
+	FixIts   []FixIt
+}
+
+func New(severity Severity, code string, span Span, format string, args ...any) Diagnostic {
+	return Diagnostic{
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+		Span:     span,
+	}
+}
+
+func Errorf(code string, span Span, format string, args ...any) Diagnostic {
+	return New(ERROR, code, span, format, args...)
+}
+
+func Warningf(code string, span Span, format string, args ...any) Diagnostic {
+	return New(WARNING, code, span, format, args...)
+}
+
+// WithNote returns a copy of the diagnostic with an extra note.
+func (d Diagnostic) WithNote(span Span, format string, args ...any) Diagnostic {
+	d.Notes = append(append([]Note{}, d.Notes...), Note{Message: fmt.Sprintf(format, args...), Span: span})
+	return d
+}
+
+// WithFixIt returns a copy of the diagnostic with an extra fix-it.
+func (d Diagnostic) WithFixIt(span Span, replacement string) Diagnostic {
+	d.FixIts = append(append([]FixIt{}, d.FixIts...), FixIt{Span: span, Replacement: replacement})
+	return d
+}
+
+// Error formats the diagnostic as "file:line:col: severity[code]: message",
+// followed by one line per note.
+func (d Diagnostic) Error() string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "%s: %s", d.Span.Start, d.Severity)
+	if d.Code != "" {
+		fmt.Fprintf(&sb, "[%s]", d.Code)
+	}
+	fmt.Fprintf(&sb, ": %s", d.Message)
+
+	for _, note := range d.Notes {
+		if note.Span.Start.IsValid() {
+			fmt.Fprintf(&sb, "\n%s: note: %s", note.Span.Start, note.Message)
+		} else {
+			fmt.Fprintf(&sb, "\n\tnote: %s", note.Message)
+		}
+	}
+
+	return sb.String()
+}
+
+// HasErrors reports whether at least one diagnostic is an error.
+func HasErrors(diagnostics []Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == ERROR {
+			return true
+		}
+	}
+	return false
+}
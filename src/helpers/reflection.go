@@ -6,14 +6,15 @@ import (
 )
 
 // Expected type is passed as a generic and this method will use reflection to compare the underlying type agains T.
-// Returns the casted type or panics if it fails. TODO: Return error instead of panic
-func ExpectType[T any](r any) T {
+// Returns the casted type, or the zero value of T and an error if it fails.
+func ExpectType[T any](r any) (T, error) {
 	expectedType := reflect.TypeOf((*T)(nil)).Elem()
 	recievedType := reflect.TypeOf(r)
 
 	if expectedType == recievedType {
-		return r.(T)
+		return r.(T), nil
 	}
 
-	panic(fmt.Sprintf("Expected '%T' but instead recived '%T' inside ExpectType[T](r)\n", expectedType, recievedType))
+	var zero T
+	return zero, fmt.Errorf("expected '%s' but instead recived '%s'", expectedType, recievedType)
 }
@@ -1,237 +1,557 @@
 package lexer
 
 import (
-	"fmt"
-	"regexp"
+	"bytes"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ZiplEix/c_parser/src/diagnostic"
+	"github.com/ZiplEix/c_parser/src/source"
 )
 
-type regexHandler func(lex *lexer, regex *regexp.Regexp)
+type lexer struct {
+	Tokens      []Token
+	Diagnostics []diagnostic.Diagnostic
+	source      []byte // text being scanned, starting at the offset base of the file
+	base        int
+	whole       string    // the entire text when it is tokenized from a string
+	reader      io.Reader // where the rest of the text is read from, nil once exhausted
+	readErr     error
+	file        string // name recorded in the positions
+	pos         int
+	line        int
+	col         int
+	start       source.Position // position of the token being scanned
+	nbTokens    int
+	comments    []Comment // comments waiting for the next token
+	newline     bool      // a line break has been seen since the last token
+	space       bool      // whitespace or a comment has been seen since the last token
+	done        bool      // the EOF token has been emitted
+	options     Options
+}
+
+// readSize is the minimum room made in the source before reading from the
+// reader.
+const readSize = 64 * 1024
+
+// fill appends the next bytes of the reader to the source. The text before
+// the token being scanned is dropped by moving the rest to the start of the
+// buffer, which doubles when the token does not leave room for a read, so
+// only the current token and the bytes after it are kept in memory. It
+// returns false when there is nothing left to read.
+func (lex *lexer) fill() bool {
+	if lex.reader == nil {
+		return false
+	}
+
+	keep := min(lex.start.Offset, lex.pos) - lex.base
+	if keep > 0 {
+		lex.source = lex.source[:copy(lex.source, lex.source[keep:])]
+		lex.base += keep
+	}
+
+	if cap(lex.source)-len(lex.source) < readSize {
+		grown := make([]byte, len(lex.source), max(2*cap(lex.source), len(lex.source)+readSize))
+		copy(grown, lex.source)
+		lex.source = grown
+	}
+
+	n, err := 0, error(nil)
+	for n == 0 && err == nil {
+		n, err = lex.reader.Read(lex.source[len(lex.source):cap(lex.source)])
+	}
+	lex.source = lex.source[:len(lex.source)+n]
+
+	if err != nil {
+		if err != io.EOF {
+			lex.readErr = err
+		}
+		lex.reader = nil
+	}
 
-type regexPattern struct {
-	regex   *regexp.Regexp
-	handler regexHandler
+	return n > 0
 }
 
-type lexer struct {
-	patterns []regexPattern
-	Tokens   []Token
-	source   string
-	pos      int
-	nbTokens int
+// available reports whether the byte n positions after the current one is in
+// the source, reading more of it if needed.
+func (lex *lexer) available(n int) bool {
+	for lex.pos-lex.base+n >= len(lex.source) {
+		if !lex.fill() {
+			return false
+		}
+	}
+	return true
 }
 
+// advanceN moves the lexer n bytes forward, keeping the line and column in
+// sync with the consumed text.
 func (lex *lexer) advanceN(n int) {
-	lex.pos += n
+	for i := 0; i < n && !lex.at_eof(); i++ {
+		if lex.at() == '\n' {
+			lex.line++
+			lex.col = 1
+		} else {
+			lex.col++
+		}
+		lex.pos++
+	}
+}
+
+func (lex *lexer) position() source.Position {
+	return source.Position{
+		File:   lex.file,
+		Offset: lex.pos,
+		Line:   lex.line,
+		Col:    lex.col,
+	}
+}
+
+// emit pushes a token of the given kind that starts at the beginning of the
+// current token and ends at the current position.
+func (lex *lexer) emit(kind TokenKind, value string) {
+	lex.push(NewToken(kind, value, lex.start, lex.position()))
+}
+
+// text returns the source text of the token being scanned. The text read
+// from a reader is copied, the buffer being reused by the next reads.
+func (lex *lexer) text() string {
+	if lex.whole != "" {
+		return lex.whole[lex.start.Offset:lex.pos]
+	}
+	return string(lex.source[lex.start.Offset-lex.base : lex.pos-lex.base])
 }
 
 func (lex *lexer) push(token Token) {
 	lex.nbTokens++
 	token.Index = lex.nbTokens
+	token.Leading = lex.comments
+	token.AtLineStart = lex.newline || lex.nbTokens == 1
+	token.HasSpace = lex.space || token.AtLineStart
+	lex.comments = nil
+	lex.newline = false
+	lex.space = false
 	lex.Tokens = append(lex.Tokens, token)
 }
 
+// pushComment attaches the comment that was just scanned as trailing trivia of
+// the previous token when it is on the same line, or keeps it as leading
+// trivia of the next token.
+func (lex *lexer) pushComment(kind TokenKind) {
+	comment := Comment{
+		Kind:  kind,
+		Text:  lex.text(),
+		Start: lex.start,
+		End:   lex.position(),
+	}
+
+	if len(lex.Tokens) > 0 && !lex.newline {
+		last := &lex.Tokens[len(lex.Tokens)-1]
+		last.Trailing = append(last.Trailing, comment)
+	} else {
+		lex.comments = append(lex.comments, comment)
+	}
+
+	if comment.Start.Line != comment.End.Line {
+		lex.newline = true
+	}
+	lex.space = true
+}
+
 func (lex *lexer) at() byte {
-	return lex.source[lex.pos]
+	return lex.peek(0)
 }
 
-func (lex *lexer) remainder() string {
-	return lex.source[lex.pos:]
+// peek returns the byte n positions after the current one, or 0 past the end
+// of the source.
+func (lex *lexer) peek(n int) byte {
+	if !lex.available(n) {
+		return 0
+	}
+	return lex.source[lex.pos-lex.base+n]
+}
+
+// remainderLookahead is the number of bytes remainder makes sure to return,
+// when the source is long enough.
+const remainderLookahead = 16
+
+func (lex *lexer) remainder() []byte {
+	lex.available(remainderLookahead - 1)
+	return lex.source[lex.pos-lex.base:]
 }
 
 func (lex *lexer) at_eof() bool {
-	return lex.pos >= len(lex.source)
+	return !lex.available(0)
 }
 
-func Tokensize(source string) []Token {
-	lex := createLexer(source)
+func (lex *lexer) errorf(code string, start source.Position, format string, args ...any) {
+	span := diagnostic.Span{Start: start, End: lex.position()}
+	lex.Diagnostics = append(lex.Diagnostics, diagnostic.Errorf(code, span, format, args...))
+}
 
-	// iterate while there are still characters to lex
-	for !lex.at_eof() {
-		matched := false
+func (lex *lexer) warningf(code string, start source.Position, format string, args ...any) {
+	span := diagnostic.Span{Start: start, End: lex.position()}
+	lex.Diagnostics = append(lex.Diagnostics, diagnostic.Warningf(code, span, format, args...))
+}
 
-		for _, pattern := range lex.patterns {
-			loc := pattern.regex.FindStringIndex(lex.remainder())
+// Tokensize splits the source into tokens in a single pass over its bytes.
+// Unrecognized characters are reported as diagnostics and skipped, so the
+// returned tokens always end with an EOF token.
+func Tokensize(source string) ([]Token, []diagnostic.Diagnostic) {
+	return TokensizeFile("", source)
+}
 
-			if loc != nil && loc[0] == 0 {
-				pattern.handler(lex, pattern.regex)
-				matched = true
-				break
-			}
-		}
+// TokensizeFile is like Tokensize, the positions of the tokens and
+// diagnostics being in the given file.
+func TokensizeFile(file, source string) ([]Token, []diagnostic.Diagnostic) {
+	return TokensizeOptions(file, source, Options{})
+}
 
-		if !matched {
-			panic(fmt.Sprintf("Lexer::Error -> unrecognized token at pos %d, near '%s'\n", lex.pos, lex.remainder()))
-		}
+// Options selects the constructs the lexer accepts. The zero value accepts
+// the ones of every revision of C.
+type Options struct {
+	// PreC23 reports the constructs introduced by C23 as errors: binary
+	// constants, digit separators and the wb suffix.
+	PreC23 bool
+}
+
+// bytesPerToken is the estimate of the average length of a token, whitespace
+// and comments included, used to size the token slice.
+const bytesPerToken = 4
+
+// TokensizeOptions is like TokensizeFile, with the given options.
+func TokensizeOptions(file, source string, opts Options) ([]Token, []diagnostic.Diagnostic) {
+	lex := createLexer(source)
+	lex.file = file
+	lex.options = opts
+	// reserving the tokens up front avoids copying them each time the slice
+	// grows
+	lex.Tokens = make([]Token, 0, len(source)/bytesPerToken+1)
+
+	for !lex.done {
+		lex.step()
 	}
 
-	lex.push(NewToken(EOF, "", 0, 0))
-	return lex.Tokens
+	return lex.Tokens, lex.Diagnostics
 }
 
-func defaultHandler(kind TokenKind, value string) regexHandler {
-	return func(lex *lexer, regex *regexp.Regexp) {
-		lex.advanceN(len(value))
-		lex.push(NewToken(kind, value, 0, 0))
+// step scans the next token, or emits the EOF token at the end of the source.
+func (lex *lexer) step() {
+	if lex.at_eof() {
+		lex.start = lex.position()
+		lex.emit(EOF, "")
+		lex.done = true
+		return
 	}
+
+	lex.start = lex.position()
+	lex.scanToken()
 }
 
 func createLexer(source string) *lexer {
 	return &lexer{
 		pos:    0,
-		source: source,
+		line:   1,
+		col:    1,
+		source: []byte(source),
+		whole:  source,
 		Tokens: make([]Token, 0),
-		patterns: []regexPattern{
-			// Whitespace
-			{regexp.MustCompile(`\s+`), skipHandler},
-
-			// Comments
-			{regexp.MustCompile(`\/\/.*`), skipHandler},
-			{regexp.MustCompile(`(?s)\/\*.*?\*\/`), skipHandler},
-			// {regexp.MustCompile(`\/\/.*`), singleLineCommentHandler},
-			// {regexp.MustCompile(`(?s)\/\*.*?\*\/`), multiLineCommentHandler},
-
-			// LITERALS
-			{regexp.MustCompile(`[0-9]+(\.[0-9]+)`), floatHandler},
-			{regexp.MustCompile(`[0-9]+`), integerHandler},
-			{regexp.MustCompile(`"((?:[^"\\]|\\.)*?)"`), stringHandler},
-			{regexp.MustCompile(`'(\\[^\n']|[^\\'\n]|\\.)'`), charHandler},
-			{regexp.MustCompile(`#include\s*<.*>`), includerHandler},
-			{regexp.MustCompile(`#include\s*".*"`), includerHandler},
-
-			// SYMBOLS
-			{regexp.MustCompile(`[a-zA-Z_][a-zA-Z0-9_]*`), symbolHandler},
-
-			// PUNCTUATION
-			{regexp.MustCompile(`\(`), defaultHandler(LPAREN, "(")},
-			{regexp.MustCompile(`\)`), defaultHandler(RPAREN, ")")},
-			{regexp.MustCompile(`\{`), defaultHandler(LBRACE, "{")},
-			{regexp.MustCompile(`\}`), defaultHandler(RBRACE, "}")},
-			{regexp.MustCompile(`\[`), defaultHandler(LBRACKET, "[")},
-			{regexp.MustCompile(`\]`), defaultHandler(RBRACKET, "]")},
-			{regexp.MustCompile(`,`), defaultHandler(COMMA, ",")},
-			{regexp.MustCompile(`;`), defaultHandler(SEMICOLON, ";")},
-			{regexp.MustCompile(`:`), defaultHandler(COLON, ":")},
-			{regexp.MustCompile(`\.`), defaultHandler(DOT, ".")},
-			{regexp.MustCompile(`\?`), defaultHandler(QUESTION, "?")},
-			{regexp.MustCompile(`#`), defaultHandler(POUND, "#")},
-
-			// COMPARISON
-			{regexp.MustCompile(`==`), defaultHandler(EQUAL, "==")},
-
-			// ASSIGNMENT
-			{regexp.MustCompile(`=`), defaultHandler(ASSIGN, "=")},
-			{regexp.MustCompile(`\+=`), defaultHandler(PLUS_ASSIGN, "+=")},
-			{regexp.MustCompile(`-=`), defaultHandler(MINUS_ASSIGN, "-=")},
-			{regexp.MustCompile(`\*=`), defaultHandler(STAR_ASSIGN, "*=")},
-			{regexp.MustCompile(`/=`), defaultHandler(SLASH_ASSIGN, "/=")},
-			{regexp.MustCompile(`%=`), defaultHandler(PERCENT_ASSIGN, "%=")},
-			{regexp.MustCompile(`&=`), defaultHandler(ESPERLUETTE_ASSIGN, "&=")},
-			{regexp.MustCompile(`\|=`), defaultHandler(PIPE_ASSIGN, "|=")},
-			{regexp.MustCompile(`\^=`), defaultHandler(CARET_ASSIGN, "^=")},
-			{regexp.MustCompile(`<<=`), defaultHandler(SHIFT_LEFT_ASSIGN, "<<=")},
-			{regexp.MustCompile(`>>=`), defaultHandler(SHIFT_RIGHT_ASSIGN, ">>=")},
-			{regexp.MustCompile(`->`), defaultHandler(ARROW, "->")},
+	}
+}
+
+// scanToken scans whatever starts at the current position: whitespace is
+// skipped, comments are kept as trivia and everything else produces one or
+// more tokens.
+func (lex *lexer) scanToken() {
+	c := lex.at()
+
+	switch {
+	case isSpace(c):
+		for !lex.at_eof() && isSpace(lex.at()) {
+			if lex.at() == '\n' {
+				lex.newline = true
+			}
+			lex.advanceN(1)
+		}
+		lex.space = true
+	case c == '\\' && (lex.peek(1) == '\n' || (lex.peek(1) == '\r' && lex.peek(2) == '\n')):
+		// line splice: the next line continues the current one
+		for lex.at() != '\n' {
+			lex.advanceN(1)
+		}
+		lex.advanceN(1)
+	case c == '/' && lex.peek(1) == '/':
+		lex.scanSingleLineComment()
+	case c == '/' && lex.peek(1) == '*':
+		lex.scanMultiLineComment()
+	case isDigit(c), c == '.' && isDigit(lex.peek(1)):
+		lex.scanNumber()
+	case c == '"', lex.encodingPrefixLength() > 0 && lex.peek(lex.encodingPrefixLength()) == '"':
+		lex.scanString()
+	case c == '\'', lex.encodingPrefixLength() > 0:
+		lex.scanChar()
+	case c == '#' && lex.scanIncluder():
+	case isIdentifierStart(c):
+		lex.scanSymbol()
+	default:
+		if !lex.scanPunctuator() {
+			char, size := utf8.DecodeRune(lex.remainder())
+			lex.advanceN(size)
+			lex.errorf(diagnostic.LEX_UNRECOGNIZED_TOKEN, lex.start, "unrecognized character %q", char)
+		}
+	}
+}
 
-			// LOGICAL
-			{regexp.MustCompile(`&&`), defaultHandler(LOGICAL_AND, "&&")},
-			{regexp.MustCompile(`\|\|`), defaultHandler(LOGICAL_OR, "||")},
-			{regexp.MustCompile(`!`), defaultHandler(LOGICAL_NOT, "!")},
+func (lex *lexer) scanSingleLineComment() {
+	for !lex.at_eof() && lex.at() != '\n' {
+		lex.advanceN(1)
+	}
 
-			// SHIFT
-			{regexp.MustCompile(`<<`), defaultHandler(SHIFT_LEFT, "<<")},
-			{regexp.MustCompile(`>>`), defaultHandler(SHIFT_RIGHT, ">>")},
+	lex.pushComment(SINGLE_LINE_COMMENT)
+}
 
-			// OPERATORS
-			{regexp.MustCompile(`\+`), defaultHandler(PLUS, "+")},
-			{regexp.MustCompile(`-`), defaultHandler(MINUS, "-")},
-			{regexp.MustCompile(`\*`), defaultHandler(STAR, "*")},
-			{regexp.MustCompile(`/`), defaultHandler(SLASH, "/")},
-			{regexp.MustCompile(`%`), defaultHandler(PERCENT, "%")},
-			{regexp.MustCompile(`&`), defaultHandler(ESPERLUETTE, "&")},
-			{regexp.MustCompile(`\|`), defaultHandler(PIPE, "|")},
-			{regexp.MustCompile(`\^`), defaultHandler(CARET, "^")},
-			{regexp.MustCompile(`~`), defaultHandler(TILDE, "~")},
+func (lex *lexer) scanMultiLineComment() {
+	lex.advanceN(2)
 
-			// COMPARISON
-			{regexp.MustCompile(`!=`), defaultHandler(NOT_EQUAL, "!=")},
-			{regexp.MustCompile(`<`), defaultHandler(LESS, "<")},
-			{regexp.MustCompile(`<=`), defaultHandler(LESS_EQUAL, "<=")},
-			{regexp.MustCompile(`>`), defaultHandler(GREATER, ">")},
-			{regexp.MustCompile(`>=`), defaultHandler(GREATER_EQUAL, ">=")},
-		},
+	for !lex.at_eof() {
+		if lex.at() == '*' && lex.peek(1) == '/' {
+			lex.advanceN(2)
+			lex.pushComment(MULTI_LINE_COMMENT)
+			return
+		}
+		lex.advanceN(1)
 	}
-}
 
-func skipHandler(lex *lexer, regex *regexp.Regexp) {
-	match := regex.FindStringIndex(lex.remainder())
-	lex.advanceN(match[1])
+	lex.errorf(diagnostic.LEX_UNTERMINATED, lex.start, "unterminated comment")
+	lex.pushComment(MULTI_LINE_COMMENT)
 }
 
-func singleLineCommentHandler(lex *lexer, regex *regexp.Regexp) {
-	match := regex.FindStringIndex(lex.remainder())
-	commentLiteral := lex.remainder()[match[0]:match[1]]
+// scanNumber consumes a preprocessing number (digits, letters, dots, digit
+// separators and signed exponents) and classifies it as an integer or a
+// floating constant.
+func (lex *lexer) scanNumber() {
+loop:
+	for {
+		c := lex.peek(0)
+
+		switch {
+		case (c == 'e' || c == 'E' || c == 'p' || c == 'P') && (lex.peek(1) == '+' || lex.peek(1) == '-'):
+			lex.advanceN(2)
+		case c == '\'' && isIdentifierPart(lex.peek(1)):
+			lex.advanceN(2)
+		case isIdentifierPart(c) || c == '.':
+			lex.advanceN(1)
+		default:
+			break loop
+		}
+	}
+
+	spelling := lex.text()
 
-	lex.push(NewToken(SINGLE_LINE_COMMENT, commentLiteral, 0, 0))
-	lex.advanceN(len(commentLiteral))
-}
+	if isFloatingSpelling(spelling) {
+		if _, err := DecodeFloat(spelling); err != nil {
+			lex.errorf(diagnostic.LEX_INVALID_NUMBER, lex.start, "%s", err)
+		} else if lex.options.PreC23 {
+			lex.checkPreC23Number(spelling, false, false)
+		}
 
-func multiLineCommentHandler(lex *lexer, regex *regexp.Regexp) {
-	match := regex.FindStringIndex(lex.remainder())
-	commentLiteral := lex.remainder()[match[0]:match[1]]
+		lex.emit(FLOATING, spelling)
+		return
+	}
 
-	lex.push(NewToken(MULTI_LINE_COMMENT, commentLiteral, 0, 0))
-	lex.advanceN(len(commentLiteral))
+	kind := INTEGER
+	literal, err := DecodeInteger(spelling)
+	if err != nil {
+		lex.errorf(diagnostic.LEX_INVALID_NUMBER, lex.start, "%s", err)
+	} else {
+		if literal.IsUnsigned {
+			kind = UNSIGNED_INTEGER
+		}
+		if lex.options.PreC23 {
+			lex.checkPreC23Number(spelling, literal.Base == 2, literal.BitPrecise)
+		}
+	}
+
+	lex.emit(kind, spelling)
 }
 
-func integerHandler(lex *lexer, regex *regexp.Regexp) {
-	match := regex.FindString(lex.remainder())
-	lex.push(NewToken(INTEGER, match, 0, 0))
-	lex.advanceN(len(match))
+// checkPreC23Number reports the parts of a valid number that only exist since
+// C23.
+func (lex *lexer) checkPreC23Number(spelling string, binary, bitPrecise bool) {
+	if binary {
+		lex.errorf(diagnostic.LEX_C23_FEATURE, lex.start, "binary constants are a C23 feature")
+	}
+	if strings.Contains(spelling, "'") {
+		lex.errorf(diagnostic.LEX_C23_FEATURE, lex.start, "digit separators are a C23 feature")
+	}
+	if bitPrecise {
+		lex.errorf(diagnostic.LEX_C23_FEATURE, lex.start, "the wb suffix is a C23 feature")
+	}
 }
 
-func floatHandler(lex *lexer, regex *regexp.Regexp) {
-	match := regex.FindString(lex.remainder())
-	lex.push(NewToken(FLOATING, match, 0, 0))
-	lex.advanceN(len(match))
+// isFloatingSpelling reports whether a preprocessing number is a floating
+// constant: it has a fraction or an exponent part.
+func isFloatingSpelling(spelling string) bool {
+	if len(spelling) > 1 && spelling[0] == '0' && (spelling[1] == 'x' || spelling[1] == 'X') {
+		return strings.ContainsAny(spelling, ".pP")
+	}
+
+	return strings.ContainsAny(spelling, ".eE")
 }
 
-func stringHandler(lex *lexer, regex *regexp.Regexp) {
-	match := regex.FindStringIndex(lex.remainder())
-	stringLiteral := lex.remainder()[match[0]+1 : match[1]-1]
+// scanQuoted consumes a literal delimited by quote, encoding prefix and
+// escape sequences included. It returns false if the literal is not
+// terminated on the same line.
+func (lex *lexer) scanQuoted(quote byte) bool {
+	for lex.at() != quote {
+		lex.advanceN(1)
+	}
+	lex.advanceN(1)
+
+	for !lex.at_eof() && lex.at() != '\n' {
+		switch lex.at() {
+		case quote:
+			lex.advanceN(1)
+			return true
+		case '\\':
+			lex.advanceN(2)
+		default:
+			lex.advanceN(1)
+		}
+	}
 
-	lex.push(NewToken(STRING, stringLiteral, 0, 0))
-	lex.advanceN(len(stringLiteral) + 2)
+	lex.errorf(diagnostic.LEX_UNTERMINATED, lex.start, "missing terminating %c character", quote)
+	return false
 }
 
-func charHandler(lex *lexer, regex *regexp.Regexp) {
-	match := regex.FindStringIndex(lex.remainder())
-	charLiteral := lex.remainder()[match[0]+1 : match[1]-1]
+func (lex *lexer) scanString() {
+	if lex.scanQuoted('"') {
+		if _, err := DecodeString(lex.text()); err != nil {
+			lex.errorf(diagnostic.LEX_INVALID_ESCAPE, lex.start, "%s", err)
+		}
+	}
 
-	lex.push(NewToken(CHARACTER, charLiteral, 0, 0))
-	lex.advanceN(len(charLiteral) + 2)
+	lex.emit(STRING, lex.text())
 }
-func includerHandler(lex *lexer, regex *regexp.Regexp) {
-	match := regex.FindStringIndex(lex.remainder())
-	includeLiteral := lex.remainder()[match[0]:match[1]]
 
-	lex.push(NewToken(INCLUDER, "", 0, 0))
+func (lex *lexer) scanChar() {
+	if lex.scanQuoted('\'') {
+		literal, err := DecodeChar(lex.text())
+		if err != nil {
+			lex.errorf(diagnostic.LEX_INVALID_ESCAPE, lex.start, "%s", err)
+		} else if literal.Count > 1 {
+			lex.warningf(diagnostic.LEX_MULTI_CHARACTER, lex.start, "multi-character character constant")
+		}
+	}
 
-	// get the index of the first " or <
-	includePath := includeLiteral[9:]
+	lex.emit(CHARACTER, lex.text())
+}
 
-	lex.push(NewToken(INCLUDE_PATH, includePath, 0, 0))
+// encodingPrefixLength returns the length of the encoding prefix (L, u, U or
+// u8) of the string or character literal starting at the current position,
+// or 0 if there is none.
+func (lex *lexer) encodingPrefixLength() int {
+	switch {
+	case lex.peek(0) == 'u' && lex.peek(1) == '8' && (lex.peek(2) == '"' || lex.peek(2) == '\''):
+		return 2
+	case (lex.peek(0) == 'L' || lex.peek(0) == 'u' || lex.peek(0) == 'U') && (lex.peek(1) == '"' || lex.peek(1) == '\''):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// scanIncluder scans an `#include <...>` or `#include "..."` line into an
+// INCLUDER and an INCLUDE_PATH token. It returns false, consuming nothing, if
+// the current '#' does not start an include.
+func (lex *lexer) scanIncluder() bool {
+	const directive = "#include"
+
+	if !bytes.HasPrefix(lex.remainder(), []byte(directive)) {
+		return false
+	}
+
+	i := len(directive)
+	for isHorizontalSpace(lex.peek(i)) {
+		i++
+	}
+
+	var closing byte
+	switch lex.peek(i) {
+	case '<':
+		closing = '>'
+	case '"':
+		closing = '"'
+	default:
+		return false
+	}
 
-	lex.advanceN(len(includeLiteral) + 2)
+	end := i + 1
+	for lex.peek(end) != closing {
+		if lex.peek(end) == '\n' || !lex.available(end) {
+			return false
+		}
+		end++
+	}
+
+	lex.advanceN(len(directive))
+	lex.emit(INCLUDER, "")
+
+	lex.advanceN(i - len(directive))
+	lex.space = i > len(directive)
+	lex.start = lex.position()
+	lex.advanceN(end + 1 - i)
+	lex.emit(INCLUDE_PATH, lex.text())
+
+	return true
 }
 
-func symbolHandler(lex *lexer, regex *regexp.Regexp) {
-	value := regex.FindString(lex.remainder())
+func (lex *lexer) scanSymbol() {
+	for isIdentifierPart(lex.peek(0)) {
+		lex.advanceN(1)
+	}
+
+	value := lex.text()
 
 	if kind, exists := reservedKeywords[value]; exists {
-		lex.push(NewToken(kind, value, 0, 0))
+		lex.emit(kind, value)
 	} else {
-		lex.push(NewToken(IDENTIFIER, value, 0, 0))
+		lex.emit(IDENTIFIER, value)
 	}
+}
+
+// scanPunctuator emits the longest punctuator matching the current position
+// (maximal munch), so `<<=` is never split into `<<` and `=`.
+func (lex *lexer) scanPunctuator() bool {
+	remainder := lex.remainder()
+
+	for length := min(maxPunctuatorLength, len(remainder)); length > 0; length-- {
+		if kind, exists := punctuators[string(remainder[:length])]; exists {
+			lex.advanceN(length)
+			lex.emit(kind, lex.text())
+			return true
+		}
+	}
+
+	return false
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+func isHorizontalSpace(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentifierStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
 
-	lex.advanceN(len(value))
+func isIdentifierPart(c byte) bool {
+	return isIdentifierStart(c) || isDigit(c)
 }
@@ -0,0 +1,72 @@
+package lexer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// generateSource returns a C source of about size bytes, made of functions
+// using comments, literals and most punctuators.
+func generateSource(size int) string {
+	var b strings.Builder
+	for i := 0; b.Len() < size; i++ {
+		fmt.Fprintf(&b, `// function number %d
+static unsigned long func_%d(const char *s, int n) {
+	/* accumulate the
+	   characters */
+	unsigned long h = 0x%xUL;
+	for (int i = 0; i < n && s[i] != '\0'; i++) {
+		h = (h << 5) + h + (unsigned char)s[i];
+		h ^= h >> 3;
+	}
+	double ratio = %d.5e-3;
+	return n > 0 ? h %% 1000003 : "fallback"[0] + ratio;
+}
+
+`, i, i, i, i)
+	}
+	return b.String()
+}
+
+// generateLongTokens returns a C source of about size bytes made of a
+// single comment followed by a single string literal, each token being far
+// longer than what the Scanner reads at once.
+func generateLongTokens(size int) string {
+	line := strings.Repeat("x", 79)
+	lines := size / 2 / (len(line) + 1)
+
+	return "/*" + strings.Repeat(line+"\n", lines) + "*/\n" +
+		"char *s = \"" + strings.Repeat(line, lines) + "\";\n"
+}
+
+var benchmarkSizes = []int{1 << 20, 4 << 20, 16 << 20}
+
+func BenchmarkTokensize(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		benchmarkSource(b, fmt.Sprintf("%dMB", size>>20), generateSource(size))
+	}
+	for _, size := range benchmarkSizes {
+		benchmarkSource(b, fmt.Sprintf("LongTokens/%dMB", size>>20), generateLongTokens(size))
+	}
+}
+
+// benchmarkSource measures the throughput of Tokensize and of the Scanner on
+// source.
+func benchmarkSource(b *testing.B, name, source string) {
+	b.Run("Tokensize/"+name, func(b *testing.B) {
+		b.SetBytes(int64(len(source)))
+		for i := 0; i < b.N; i++ {
+			Tokensize(source)
+		}
+	})
+
+	b.Run("Scanner/"+name, func(b *testing.B) {
+		b.SetBytes(int64(len(source)))
+		for i := 0; i < b.N; i++ {
+			scanner := NewScanner("bench.c", strings.NewReader(source))
+			for range scanner.All() {
+			}
+		}
+	})
+}
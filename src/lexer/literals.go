@@ -0,0 +1,446 @@
+package lexer
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// IntegerLiteral is the decoded form of an INTEGER or UNSIGNED_INTEGER token.
+type IntegerLiteral struct {
+	Base       int    // 2, 8, 10 or 16
+	Digits     string // digits without base prefix, suffix and separators
+	Suffix     string // suffix as written, e.g. "UL"
+	IsUnsigned bool   // u or U suffix
+	LongCount  int    // 0, 1 (l suffix) or 2 (ll suffix)
+	BitPrecise bool   // wb or WB suffix (_BitInt)
+}
+
+// DecodeInteger splits the spelling of a C23 integer constant into its base,
+// digits and suffix. Digit separators (') are removed from the digits.
+func DecodeInteger(spelling string) (IntegerLiteral, error) {
+	literal := IntegerLiteral{Base: 10}
+	body := spelling
+
+	switch {
+	case len(body) > 1 && body[0] == '0' && (body[1] == 'x' || body[1] == 'X'):
+		literal.Base = 16
+		body = body[2:]
+	case len(body) > 1 && body[0] == '0' && (body[1] == 'b' || body[1] == 'B'):
+		literal.Base = 2
+		body = body[2:]
+	case len(body) > 1 && body[0] == '0':
+		literal.Base = 8
+		body = body[1:]
+	}
+
+	end := 0
+	for end < len(body) && (isDigitOfBase(body[end], literal.Base) || body[end] == '\'' || (literal.Base == 8 && isDigit(body[end]))) {
+		end++
+	}
+
+	digits, suffix := body[:end], body[end:]
+
+	if digits == "" {
+		if literal.Base == 8 {
+			// a lone "0" is lexed as an octal zero
+			digits = "0"
+		} else {
+			return literal, fmt.Errorf("invalid integer constant '%s': missing digits after base prefix", spelling)
+		}
+	}
+
+	if digits[0] == '\'' || digits[len(digits)-1] == '\'' || strings.Contains(digits, "''") {
+		return literal, fmt.Errorf("invalid digit separator in integer constant '%s'", spelling)
+	}
+
+	digits = strings.ReplaceAll(digits, "'", "")
+	for _, digit := range []byte(digits) {
+		if !isDigitOfBase(digit, literal.Base) {
+			return literal, fmt.Errorf("invalid digit '%c' in %s constant '%s'", digit, baseName(literal.Base), spelling)
+		}
+	}
+
+	literal.Digits = digits
+	literal.Suffix = suffix
+
+	if !decodeIntegerSuffix(&literal, suffix) {
+		return literal, fmt.Errorf("invalid suffix '%s' on integer constant '%s'", suffix, spelling)
+	}
+
+	return literal, nil
+}
+
+// decodeIntegerSuffix fills the suffix information of literal and reports
+// whether suffix is a valid combination of u, l, ll and wb.
+func decodeIntegerSuffix(literal *IntegerLiteral, suffix string) bool {
+	for suffix != "" {
+		switch {
+		case (suffix[0] == 'u' || suffix[0] == 'U') && !literal.IsUnsigned:
+			literal.IsUnsigned = true
+			suffix = suffix[1:]
+		case (strings.HasPrefix(suffix, "ll") || strings.HasPrefix(suffix, "LL")) && literal.LongCount == 0 && !literal.BitPrecise:
+			literal.LongCount = 2
+			suffix = suffix[2:]
+		case (suffix[0] == 'l' || suffix[0] == 'L') && literal.LongCount == 0 && !literal.BitPrecise:
+			literal.LongCount = 1
+			suffix = suffix[1:]
+		case (strings.HasPrefix(suffix, "wb") || strings.HasPrefix(suffix, "WB")) && literal.LongCount == 0 && !literal.BitPrecise:
+			literal.BitPrecise = true
+			suffix = suffix[2:]
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func isDigitOfBase(c byte, base int) bool {
+	switch base {
+	case 2:
+		return c == '0' || c == '1'
+	case 8:
+		return c >= '0' && c <= '7'
+	case 16:
+		return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+	default:
+		return isDigit(c)
+	}
+}
+
+func baseName(base int) string {
+	switch base {
+	case 2:
+		return "binary"
+	case 8:
+		return "octal"
+	case 16:
+		return "hexadecimal"
+	default:
+		return "decimal"
+	}
+}
+
+// FloatLiteral is the decoded form of a FLOATING token.
+type FloatLiteral struct {
+	IsHex  bool
+	Number string // literal without suffix and separators, accepted by strconv.ParseFloat
+	Suffix string // suffix as written: "", "f", "F", "l" or "L"
+}
+
+// DecodeFloat validates the spelling of a decimal or hexadecimal floating
+// constant and splits it into its number and suffix.
+func DecodeFloat(spelling string) (FloatLiteral, error) {
+	literal := FloatLiteral{}
+	body := spelling
+	base := 10
+	prefix := ""
+
+	if len(body) > 1 && body[0] == '0' && (body[1] == 'x' || body[1] == 'X') {
+		literal.IsHex = true
+		base = 16
+		prefix = body[:2]
+		body = body[2:]
+	}
+
+	i := 0
+	mantissaDigits := 0
+	for i < len(body) && (isDigitOfBase(body[i], base) || body[i] == '\'') {
+		if body[i] != '\'' {
+			mantissaDigits++
+		}
+		i++
+	}
+	if i < len(body) && body[i] == '.' {
+		i++
+		for i < len(body) && (isDigitOfBase(body[i], base) || body[i] == '\'') {
+			if body[i] != '\'' {
+				mantissaDigits++
+			}
+			i++
+		}
+	}
+
+	if mantissaDigits == 0 {
+		return literal, fmt.Errorf("invalid floating constant '%s': missing digits", spelling)
+	}
+
+	hasExponent := i < len(body) && ((!literal.IsHex && (body[i] == 'e' || body[i] == 'E')) || (literal.IsHex && (body[i] == 'p' || body[i] == 'P')))
+	if hasExponent {
+		i++
+		if i < len(body) && (body[i] == '+' || body[i] == '-') {
+			i++
+		}
+
+		exponentStart := i
+		for i < len(body) && (isDigit(body[i]) || body[i] == '\'') {
+			i++
+		}
+
+		if i == exponentStart {
+			return literal, fmt.Errorf("exponent has no digits in floating constant '%s'", spelling)
+		}
+	} else if literal.IsHex {
+		return literal, fmt.Errorf("hexadecimal floating constant '%s' requires an exponent", spelling)
+	}
+
+	number, suffix := body[:i], body[i:]
+
+	if strings.HasPrefix(number, "'") || strings.Contains(number, "''") || strings.HasSuffix(number, "'") ||
+		strings.Contains(number, "'.") || strings.Contains(number, ".'") {
+		return literal, fmt.Errorf("invalid digit separator in floating constant '%s'", spelling)
+	}
+
+	switch suffix {
+	case "", "f", "F", "l", "L":
+	default:
+		return literal, fmt.Errorf("invalid suffix '%s' on floating constant '%s'", suffix, spelling)
+	}
+
+	literal.Number = prefix + strings.ReplaceAll(number, "'", "")
+	literal.Suffix = suffix
+
+	return literal, nil
+}
+
+// StringLiteral is the decoded form of a STRING token.
+type StringLiteral struct {
+	Prefix string // encoding prefix: "", "L", "u", "U" or "u8"
+	Value  string // decoded content: bytes for narrow strings, UTF-8 encoded code units otherwise
+}
+
+// CharLiteral is the decoded form of a CHARACTER token.
+type CharLiteral struct {
+	Prefix string // encoding prefix: "", "L", "u", "U" or "u8"
+	Value  int64  // value of the constant, as the C compiler computes it
+	Count  int    // number of characters, more than one is a multi-character constant
+}
+
+// splitEncodingPrefix separates the encoding prefix of a string or character
+// literal from the quoted part.
+func splitEncodingPrefix(spelling string) (string, string) {
+	for _, prefix := range []string{"u8", "L", "u", "U"} {
+		if strings.HasPrefix(spelling, prefix) {
+			return prefix, spelling[len(prefix):]
+		}
+	}
+	return "", spelling
+}
+
+// unitBits returns the width of a code unit for the given encoding prefix.
+func unitBits(prefix string) uint {
+	switch prefix {
+	case "u":
+		return 16
+	case "U", "L":
+		return 32
+	default:
+		return 8
+	}
+}
+
+// decodeQuoted decodes the escape sequences of a quoted literal. Every element
+// of the result is either a code unit (from a plain character of a narrow
+// literal or a numeric escape) or a code point (from a universal character
+// name or a plain character of a wide literal).
+func decodeQuoted(spelling string, quote byte) (prefix string, units []uint32, isCodePoint []bool, err error) {
+	prefix, quoted := splitEncodingPrefix(spelling)
+
+	if len(quoted) < 2 || quoted[0] != quote || quoted[len(quoted)-1] != quote {
+		return prefix, nil, nil, fmt.Errorf("missing terminating %c character", quote)
+	}
+
+	body := quoted[1 : len(quoted)-1]
+	bits := unitBits(prefix)
+
+	for i := 0; i < len(body); {
+		if body[i] != '\\' {
+			if bits == 8 {
+				units = append(units, uint32(body[i]))
+				isCodePoint = append(isCodePoint, false)
+				i++
+				continue
+			}
+
+			char, size := utf8.DecodeRuneInString(body[i:])
+			units = append(units, uint32(char))
+			isCodePoint = append(isCodePoint, true)
+			i += size
+			continue
+		}
+
+		value, codePoint, next, err := decodeEscape(body, i, bits)
+		if err != nil {
+			return prefix, nil, nil, err
+		}
+
+		units = append(units, value)
+		isCodePoint = append(isCodePoint, codePoint)
+		i = next
+	}
+
+	return prefix, units, isCodePoint, nil
+}
+
+// decodeEscape decodes the escape sequence starting at body[i] (a backslash).
+// It returns the value, whether it is a code point (universal character name)
+// rather than a code unit, and the index following the sequence.
+func decodeEscape(body string, i int, bits uint) (uint32, bool, int, error) {
+	if i+1 >= len(body) {
+		return 0, false, i, fmt.Errorf("incomplete escape sequence")
+	}
+
+	switch c := body[i+1]; c {
+	case '\'', '"', '?', '\\':
+		return uint32(c), false, i + 2, nil
+	case 'a':
+		return 0x07, false, i + 2, nil
+	case 'b':
+		return 0x08, false, i + 2, nil
+	case 'f':
+		return 0x0C, false, i + 2, nil
+	case 'n':
+		return 0x0A, false, i + 2, nil
+	case 'r':
+		return 0x0D, false, i + 2, nil
+	case 't':
+		return 0x09, false, i + 2, nil
+	case 'v':
+		return 0x0B, false, i + 2, nil
+	case 'x':
+		end := i + 2
+		value := uint64(0)
+		for end < len(body) && isDigitOfBase(body[end], 16) {
+			value = value<<4 | uint64(hexValue(body[end]))
+			if value >= 1<<bits {
+				return 0, false, end, fmt.Errorf("hex escape sequence out of range")
+			}
+			end++
+		}
+		if end == i+2 {
+			return 0, false, end, fmt.Errorf("\\x used with no following hex digits")
+		}
+		return uint32(value), false, end, nil
+	case 'u', 'U':
+		length := 4
+		if c == 'U' {
+			length = 8
+		}
+
+		end := i + 2 + length
+		if end > len(body) {
+			return 0, false, len(body), fmt.Errorf("incomplete universal character name \\%c", c)
+		}
+
+		value := uint32(0)
+		for _, digit := range []byte(body[i+2 : end]) {
+			if !isDigitOfBase(digit, 16) {
+				return 0, false, end, fmt.Errorf("incomplete universal character name %s", body[i:end])
+			}
+			value = value<<4 | hexValue(digit)
+		}
+
+		if value > utf8.MaxRune || (value >= 0xD800 && value <= 0xDFFF) || (value < 0xA0 && value != '$' && value != '@' && value != '`') {
+			return 0, false, end, fmt.Errorf("%s is not a valid universal character", body[i:end])
+		}
+		return value, true, end, nil
+	default:
+		if c >= '0' && c <= '7' {
+			end := i + 1
+			value := uint32(0)
+			for end < len(body) && end < i+4 && body[end] >= '0' && body[end] <= '7' {
+				value = value<<3 | uint32(body[end]-'0')
+				end++
+			}
+			if uint64(value) >= 1<<bits {
+				return 0, false, end, fmt.Errorf("octal escape sequence out of range")
+			}
+			return value, false, end, nil
+		}
+
+		return 0, false, i + 2, fmt.Errorf("unknown escape sequence '\\%c'", c)
+	}
+}
+
+func hexValue(c byte) uint32 {
+	switch {
+	case c >= 'a' && c <= 'f':
+		return uint32(c-'a') + 10
+	case c >= 'A' && c <= 'F':
+		return uint32(c-'A') + 10
+	default:
+		return uint32(c - '0')
+	}
+}
+
+// DecodeString validates a string literal, prefix and quotes included, and
+// decodes its escape sequences.
+func DecodeString(spelling string) (StringLiteral, error) {
+	prefix, units, isCodePoint, err := decodeQuoted(spelling, '"')
+	literal := StringLiteral{Prefix: prefix}
+	if err != nil {
+		return literal, err
+	}
+
+	var sb strings.Builder
+	for i, unit := range units {
+		if unitBits(prefix) == 8 && !isCodePoint[i] {
+			sb.WriteByte(byte(unit))
+		} else {
+			sb.WriteRune(rune(unit))
+		}
+	}
+
+	literal.Value = sb.String()
+	return literal, nil
+}
+
+// DecodeChar validates a character constant, prefix and quotes included, and
+// computes its value. Plain multi-character constants are combined the way gcc
+// and clang do, one byte per character.
+func DecodeChar(spelling string) (CharLiteral, error) {
+	prefix, units, isCodePoint, err := decodeQuoted(spelling, '\'')
+	literal := CharLiteral{Prefix: prefix}
+	if err != nil {
+		return literal, err
+	}
+
+	// universal character names are UTF-8 encoded in narrow constants
+	if unitBits(prefix) == 8 {
+		bytes := []uint32{}
+		for i, unit := range units {
+			if !isCodePoint[i] {
+				bytes = append(bytes, unit)
+				continue
+			}
+			for _, b := range []byte(string(rune(unit))) {
+				bytes = append(bytes, uint32(b))
+			}
+		}
+		units = bytes
+	}
+
+	literal.Count = len(units)
+
+	switch {
+	case len(units) == 0:
+		return literal, fmt.Errorf("empty character constant")
+	case prefix == "" && len(units) == 1:
+		// char is signed on the targets we support
+		literal.Value = int64(int8(units[0]))
+	case prefix == "":
+		for _, unit := range units {
+			literal.Value = literal.Value<<8 | int64(unit)
+		}
+		literal.Value = int64(int32(literal.Value))
+	case len(units) > 1:
+		return literal, fmt.Errorf("character constant '%s' has more than one character", spelling)
+	case prefix == "u" && units[0] > 0xFFFF:
+		return literal, fmt.Errorf("character '%s' too large for its type", spelling)
+	default:
+		literal.Value = int64(units[0])
+	}
+
+	return literal, nil
+}
@@ -0,0 +1,273 @@
+package lexer
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestDecodeInteger(t *testing.T) {
+	tests := []struct {
+		spelling string
+		want     IntegerLiteral
+	}{
+		{"0", IntegerLiteral{Base: 10, Digits: "0"}},
+		{"42", IntegerLiteral{Base: 10, Digits: "42"}},
+		{"0777", IntegerLiteral{Base: 8, Digits: "777"}},
+		{"0xFFffFFff", IntegerLiteral{Base: 16, Digits: "FFffFFff"}},
+		{"0B101", IntegerLiteral{Base: 2, Digits: "101"}},
+		{"1'000'000", IntegerLiteral{Base: 10, Digits: "1000000"}},
+		{"0xff'ff", IntegerLiteral{Base: 16, Digits: "ffff"}},
+		{"42u", IntegerLiteral{Base: 10, Digits: "42", Suffix: "u", IsUnsigned: true}},
+		{"42L", IntegerLiteral{Base: 10, Digits: "42", Suffix: "L", LongCount: 1}},
+		{"42ull", IntegerLiteral{Base: 10, Digits: "42", Suffix: "ull", IsUnsigned: true, LongCount: 2}},
+		{"42LLU", IntegerLiteral{Base: 10, Digits: "42", Suffix: "LLU", IsUnsigned: true, LongCount: 2}},
+		{"42lu", IntegerLiteral{Base: 10, Digits: "42", Suffix: "lu", IsUnsigned: true, LongCount: 1}},
+		{"42wb", IntegerLiteral{Base: 10, Digits: "42", Suffix: "wb", BitPrecise: true}},
+		{"42uWB", IntegerLiteral{Base: 10, Digits: "42", Suffix: "uWB", IsUnsigned: true, BitPrecise: true}},
+		{"18446744073709551616", IntegerLiteral{Base: 10, Digits: "18446744073709551616"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.spelling, func(t *testing.T) {
+			got, err := DecodeInteger(test.spelling)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDecodeIntegerErrors(t *testing.T) {
+	tests := []string{
+		"0x",
+		"0b",
+		"0b102",
+		"089",
+		"0xfg",
+		"1''0",
+		"1'",
+		"0x'",
+		"0x'ff",
+		"42lL",
+		"42uu",
+		"42lll",
+		"42llwb",
+		"42wbl",
+		"42z",
+	}
+
+	for _, spelling := range tests {
+		t.Run(spelling, func(t *testing.T) {
+			if got, err := DecodeInteger(spelling); err == nil {
+				t.Errorf("got %+v, want an error", got)
+			}
+		})
+	}
+}
+
+func TestDecodeFloat(t *testing.T) {
+	tests := []struct {
+		spelling string
+		want     FloatLiteral
+	}{
+		{"1.5", FloatLiteral{Number: "1.5"}},
+		{"1.", FloatLiteral{Number: "1."}},
+		{".5", FloatLiteral{Number: ".5"}},
+		{"1e10", FloatLiteral{Number: "1e10"}},
+		{"1.5E-3", FloatLiteral{Number: "1.5E-3"}},
+		{"2e+8f", FloatLiteral{Number: "2e+8", Suffix: "f"}},
+		{"1.0L", FloatLiteral{Number: "1.0", Suffix: "L"}},
+		{"1'000.000'5", FloatLiteral{Number: "1000.0005"}},
+		{"0x1.8p3", FloatLiteral{IsHex: true, Number: "0x1.8p3"}},
+		{"0X.8P-1", FloatLiteral{IsHex: true, Number: "0X.8P-1"}},
+		{"0x1p+4F", FloatLiteral{IsHex: true, Number: "0x1p+4", Suffix: "F"}},
+		{"0xa.bp0l", FloatLiteral{IsHex: true, Number: "0xa.bp0", Suffix: "l"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.spelling, func(t *testing.T) {
+			got, err := DecodeFloat(test.spelling)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDecodeFloatErrors(t *testing.T) {
+	tests := []string{
+		".",
+		"1e",
+		"1e+",
+		"1.5p3",
+		"0x1.8",
+		"0x.p1",
+		"0x1.8e3",
+		"1.0ff",
+		"1.0u",
+		"1'.5",
+		"1.'5",
+		"1.5'",
+		"1''0.5",
+	}
+
+	for _, spelling := range tests {
+		t.Run(spelling, func(t *testing.T) {
+			if got, err := DecodeFloat(spelling); err == nil {
+				t.Errorf("got %+v, want an error", got)
+			}
+		})
+	}
+}
+
+func TestDecodeString(t *testing.T) {
+	tests := []struct {
+		spelling string
+		want     StringLiteral
+	}{
+		{`""`, StringLiteral{}},
+		{`"a\tb\n"`, StringLiteral{Value: "a\tb\n"}},
+		{`"\'\"\?\\"`, StringLiteral{Value: `'"?\`}},
+		{`"\a\b\f\r\v"`, StringLiteral{Value: "\a\b\f\r\v"}},
+		{`"\0"`, StringLiteral{Value: "\x00"}},
+		{`"\101\1021"`, StringLiteral{Value: "AB1"}},
+		{`"\x41\xff"`, StringLiteral{Value: "A\xff"}},
+		{`"é\U0001F600"`, StringLiteral{Value: "é😀"}},
+		{`"é"`, StringLiteral{Value: "é"}},
+		{`u8"é\x41"`, StringLiteral{Prefix: "u8", Value: "éA"}},
+		{`L"é\x263A"`, StringLiteral{Prefix: "L", Value: "é☺"}},
+		{`u"\xFFFF"`, StringLiteral{Prefix: "u", Value: "￿"}},
+		{`U"\U0001F600"`, StringLiteral{Prefix: "U", Value: "😀"}},
+		{`"\u0024\u0040\u0060"`, StringLiteral{Value: "$@`"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.spelling, func(t *testing.T) {
+			got, err := DecodeString(test.spelling)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDecodeStringErrors(t *testing.T) {
+	tests := []string{
+		`"abc`,
+		`"\q"`,
+		`"\x"`,
+		`"\x100"`,
+		`"\400"`,
+		`u"\x10000"`,
+		`"\u12"`,
+		`"\u12g4"`,
+		`"\uD800"`,
+		`"\u0041"`,
+		`"\U00110000"`,
+		`"\"`,
+	}
+
+	for _, spelling := range tests {
+		t.Run(spelling, func(t *testing.T) {
+			if got, err := DecodeString(spelling); err == nil {
+				t.Errorf("got %+v, want an error", got)
+			}
+		})
+	}
+}
+
+func TestDecodeChar(t *testing.T) {
+	tests := []struct {
+		spelling string
+		want     CharLiteral
+	}{
+		{`'a'`, CharLiteral{Value: 'a', Count: 1}},
+		{`'\n'`, CharLiteral{Value: '\n', Count: 1}},
+		{`'\0'`, CharLiteral{Value: 0, Count: 1}},
+		{`'\''`, CharLiteral{Value: '\'', Count: 1}},
+		{`'"'`, CharLiteral{Value: '"', Count: 1}},
+		{`'\377'`, CharLiteral{Value: -1, Count: 1}},
+		{`'\xff'`, CharLiteral{Value: -1, Count: 1}},
+		{`'\x7f'`, CharLiteral{Value: 0x7f, Count: 1}},
+		{`'ab'`, CharLiteral{Value: 0x6162, Count: 2}},
+		{`'abcd'`, CharLiteral{Value: 0x61626364, Count: 4}},
+		{`'é'`, CharLiteral{Value: 0xC3A9, Count: 2}},
+		{`u8'a'`, CharLiteral{Prefix: "u8", Value: 'a', Count: 1}},
+		{`L'é'`, CharLiteral{Prefix: "L", Value: 0xE9, Count: 1}},
+		{`L'\xFFFFFFFF'`, CharLiteral{Prefix: "L", Value: 0xFFFFFFFF, Count: 1}},
+		{`u'é'`, CharLiteral{Prefix: "u", Value: 0xE9, Count: 1}},
+		{`u'￿'`, CharLiteral{Prefix: "u", Value: 0xFFFF, Count: 1}},
+		{`U'\U0001F600'`, CharLiteral{Prefix: "U", Value: 0x1F600, Count: 1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.spelling, func(t *testing.T) {
+			got, err := DecodeChar(test.spelling)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCharErrors(t *testing.T) {
+	tests := []string{
+		`''`,
+		`'a`,
+		`'\q'`,
+		`'\x100'`,
+		`L'ab'`,
+		`U'ab'`,
+		`u'\U0001F600'`,
+		`u'😀'`,
+	}
+
+	for _, spelling := range tests {
+		t.Run(spelling, func(t *testing.T) {
+			if got, err := DecodeChar(spelling); err == nil {
+				t.Errorf("got %+v, want an error", got)
+			}
+		})
+	}
+}
+
+func TestCharacterDiagnostics(t *testing.T) {
+	tests := []struct {
+		source string
+		want   []string
+	}{
+		{`'a'`, nil},
+		{`'ab'`, []string{"warning[L0005]"}},
+		{`L'ab'`, []string{"error[L0004]"}},
+		{`'\q'`, []string{"error[L0004]"}},
+		{`"\x100"`, []string{"error[L0004]"}},
+		{`'a`, []string{"error[L0002]"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			_, diagnostics := Tokensize(test.source)
+
+			got := []string{}
+			for _, d := range diagnostics {
+				got = append(got, fmt.Sprintf("%s[%s]", d.Severity, d.Code))
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package lexer
+
+import (
+	"slices"
+	"testing"
+)
+
+// kinds returns the kinds of the tokens, without the final EOF.
+func kinds(tokens []Token) []TokenKind {
+	result := []TokenKind{}
+	for _, token := range tokens {
+		if token.Kind != EOF {
+			result = append(result, token.Kind)
+		}
+	}
+	return result
+}
+
+func TestPunctuators(t *testing.T) {
+	for spelling, kind := range punctuators {
+		t.Run(spelling, func(t *testing.T) {
+			tokens, diagnostics := Tokensize(spelling)
+			if len(diagnostics) > 0 {
+				t.Fatalf("unexpected diagnostics: %v", diagnostics)
+			}
+
+			got := kinds(tokens)
+			if len(got) != 1 || got[0] != kind {
+				t.Fatalf("got %v, want a single %s", got, TokenKindString(kind))
+			}
+			if tokens[0].Value != spelling {
+				t.Errorf("got value %q, want %q", tokens[0].Value, spelling)
+			}
+		})
+	}
+}
+
+func TestPunctuatorsMaximalMunch(t *testing.T) {
+	tests := []struct {
+		source string
+		want   []TokenKind
+	}{
+		{"<<=", []TokenKind{SHIFT_LEFT_ASSIGN}},
+		{">>=", []TokenKind{SHIFT_RIGHT_ASSIGN}},
+		{"<<==", []TokenKind{SHIFT_LEFT_ASSIGN, ASSIGN}},
+		{"a+++b", []TokenKind{IDENTIFIER, INCREMENT, PLUS, IDENTIFIER}},
+		{"a+++++b", []TokenKind{IDENTIFIER, INCREMENT, INCREMENT, PLUS, IDENTIFIER}},
+		{"a---b", []TokenKind{IDENTIFIER, DECREMENT, MINUS, IDENTIFIER}},
+		{"...", []TokenKind{ELLIPSIS}},
+		{"..", []TokenKind{DOT, DOT}},
+		{"....", []TokenKind{ELLIPSIS, DOT}},
+		{"%:%:", []TokenKind{POUND_POUND}},
+		{"%:%", []TokenKind{POUND, PERCENT}},
+		{"<::>", []TokenKind{LBRACKET, RBRACKET}},
+		{"<:::>", []TokenKind{LBRACKET, COLON, RBRACKET}},
+		{"->>", []TokenKind{ARROW, GREATER}},
+		{"&&=", []TokenKind{LOGICAL_AND, ASSIGN}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			tokens, diagnostics := Tokensize(test.source)
+			if len(diagnostics) > 0 {
+				t.Fatalf("unexpected diagnostics: %v", diagnostics)
+			}
+
+			if got := kinds(tokens); !slices.Equal(got, test.want) {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
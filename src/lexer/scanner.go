@@ -0,0 +1,120 @@
+package lexer
+
+import (
+	"io"
+	"iter"
+	"slices"
+
+	"github.com/ZiplEix/c_parser/src/diagnostic"
+)
+
+// TokenSource hands out tokens one at a time. The last token is EOF, it is
+// returned again by every later call.
+type TokenSource interface {
+	Next() Token
+}
+
+// SliceSource is a TokenSource over tokens already in memory, like the ones
+// returned by Tokensize.
+type SliceSource struct {
+	tokens []Token
+	pos    int
+}
+
+func NewSliceSource(tokens []Token) *SliceSource {
+	return &SliceSource{tokens: tokens}
+}
+
+func (s *SliceSource) Next() Token {
+	if s.pos >= len(s.tokens) {
+		if len(s.tokens) > 0 && s.tokens[len(s.tokens)-1].Kind == EOF {
+			return s.tokens[len(s.tokens)-1]
+		}
+		return Token{Kind: EOF}
+	}
+
+	token := s.tokens[s.pos]
+	if token.Kind != EOF {
+		s.pos++
+	}
+	return token
+}
+
+// Scanner tokenizes text read from an io.Reader on demand. Only the tokens
+// not consumed yet and the text of the token being scanned are kept in
+// memory, so sources of any size can be processed.
+//
+// A token is handed out once the next one has been scanned, when its trailing
+// comments are known.
+type Scanner struct {
+	lex *lexer
+}
+
+// NewScanner returns a Scanner reading from r, file being the name recorded
+// in the positions.
+func NewScanner(file string, r io.Reader) *Scanner {
+	return NewScannerOptions(file, r, Options{})
+}
+
+// NewScannerOptions is like NewScanner, with the given options.
+func NewScannerOptions(file string, r io.Reader, opts Options) *Scanner {
+	lex := createLexer("")
+	lex.file = file
+	lex.reader = r
+	lex.options = opts
+
+	return &Scanner{lex: lex}
+}
+
+// scan makes the first n+1 waiting tokens final, or stops at the end of the
+// source.
+func (s *Scanner) scan(n int) {
+	for !s.lex.done && len(s.lex.Tokens) <= n+1 {
+		s.lex.step()
+	}
+}
+
+// Peek returns the token n positions after the next one without consuming
+// it, Peek(0) being the token Next returns. Past the end it returns EOF.
+func (s *Scanner) Peek(n int) Token {
+	s.scan(n)
+
+	if n < len(s.lex.Tokens) {
+		return s.lex.Tokens[n]
+	}
+	return s.lex.Tokens[len(s.lex.Tokens)-1]
+}
+
+// Next consumes and returns the next token. Once the EOF token is reached it
+// is returned by every call.
+func (s *Scanner) Next() Token {
+	token := s.Peek(0)
+
+	if token.Kind != EOF {
+		s.lex.Tokens = s.lex.Tokens[1:]
+	}
+	return token
+}
+
+// All returns an iterator over the remaining tokens, EOF included.
+func (s *Scanner) All() iter.Seq[Token] {
+	return func(yield func(Token) bool) {
+		for {
+			token := s.Next()
+			if !yield(token) || token.Kind == EOF {
+				return
+			}
+		}
+	}
+}
+
+// Diagnostics returns the errors and warnings found in the text scanned so
+// far. Appending to the result never changes the ones of the Scanner.
+func (s *Scanner) Diagnostics() []diagnostic.Diagnostic {
+	return slices.Clip(s.lex.Diagnostics)
+}
+
+// Err returns the error returned by the reader, if it is not io.EOF.
+func (s *Scanner) Err() error {
+	return s.lex.readErr
+}
@@ -0,0 +1,50 @@
+package lexer
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// TestScannerLongTokens checks that tokens longer than a read are scanned by
+// the Scanner as by Tokensize, whatever the size of the reads.
+func TestScannerLongTokens(t *testing.T) {
+	source := generateLongTokens(3*readSize) + "int x = 1; // end\n"
+	want, _ := Tokensize(source)
+
+	readers := map[string]func() io.Reader{
+		"full reads": func() io.Reader { return strings.NewReader(source) },
+		"half reads": func() io.Reader { return iotest.HalfReader(strings.NewReader(source)) },
+		"one byte":   func() io.Reader { return iotest.OneByteReader(strings.NewReader(source)) },
+	}
+
+	for name, reader := range readers {
+		t.Run(name, func(t *testing.T) {
+			scanner := NewScanner("", reader())
+
+			i := 0
+			for token := range scanner.All() {
+				if i >= len(want) {
+					t.Fatalf("got more than %d tokens", len(want))
+				}
+				if token.Kind != want[i].Kind || token.Value != want[i].Value || token.End != want[i].End {
+					t.Fatalf("token %d: got %s %.20q ending at %v, want %s %.20q ending at %v", i,
+						TokenKindString(token.Kind), token.Value, token.End, TokenKindString(want[i].Kind), want[i].Value, want[i].End)
+				}
+				if !slices.Equal(token.Leading, want[i].Leading) || !slices.Equal(token.Trailing, want[i].Trailing) {
+					t.Fatalf("token %d: the comments differ from the ones of Tokensize", i)
+				}
+				i++
+			}
+
+			if i != len(want) {
+				t.Errorf("got %d tokens, want %d", i, len(want))
+			}
+			if len(scanner.Diagnostics()) > 0 {
+				t.Errorf("unexpected diagnostics: %v", scanner.Diagnostics())
+			}
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package lexer
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/ZiplEix/c_parser/src/source"
+)
 
 type TokenKind int
 
@@ -16,18 +20,20 @@ const (
 	IDENTIFIER // [a-zA-Z_][a-zA-Z0-9_]*
 
 	// PUNCTUATION
-	LPAREN    // (
-	RPAREN    // )
-	LBRACE    // {
-	RBRACE    // }
-	LBRACKET  // [
-	RBRACKET  // ]
-	COMMA     // ,
-	SEMICOLON // ;
-	COLON     // :
-	DOT       // .
-	QUESTION  // ?
-	POUND     // #
+	LPAREN      // (
+	RPAREN      // )
+	LBRACE      // {
+	RBRACE      // }
+	LBRACKET    // [
+	RBRACKET    // ]
+	COMMA       // ,
+	SEMICOLON   // ;
+	COLON       // :
+	DOT         // .
+	ELLIPSIS    // ...
+	QUESTION    // ?
+	POUND       // #
+	POUND_POUND // ##
 
 	// LITERALS
 	INTEGER          // -13 || 42
@@ -37,6 +43,9 @@ const (
 	STRING           // "..."
 	INCLUDER         // #include <...> || #include "..."
 	INCLUDE_PATH     // <...> || "..."
+	DIRECTIVE        // #define ... (whole line, in preprocessor pass-through mode)
+	INCLUDE_START    // start of the tokens of an included header, the value is its path
+	INCLUDE_END      // end of the tokens of an included header
 
 	// OPERATORS
 	PLUS        // +
@@ -48,6 +57,8 @@ const (
 	PIPE        // |
 	CARET       // ^
 	TILDE       // ~
+	INCREMENT   // ++
+	DECREMENT   // --
 
 	// COMPARISON
 	EQUAL         // ==
@@ -168,12 +179,101 @@ var reservedKeywords = map[string]TokenKind{
 	"volatile": VOLATILE,
 }
 
-type Token struct {
+// punctuators maps the spelling of every C11 punctuator, digraphs included,
+// to its kind. The lexer always picks the longest spelling that matches.
+var punctuators = map[string]TokenKind{
+	// PUNCTUATION
+	"(":   LPAREN,
+	")":   RPAREN,
+	"{":   LBRACE,
+	"}":   RBRACE,
+	"[":   LBRACKET,
+	"]":   RBRACKET,
+	",":   COMMA,
+	";":   SEMICOLON,
+	":":   COLON,
+	".":   DOT,
+	"...": ELLIPSIS,
+	"?":   QUESTION,
+	"#":   POUND,
+	"##":  POUND_POUND,
+
+	// DIGRAPHS
+	"<:":   LBRACKET,
+	":>":   RBRACKET,
+	"<%":   LBRACE,
+	"%>":   RBRACE,
+	"%:":   POUND,
+	"%:%:": POUND_POUND,
+
+	// OPERATORS
+	"+":  PLUS,
+	"-":  MINUS,
+	"*":  STAR,
+	"/":  SLASH,
+	"%":  PERCENT,
+	"&":  ESPERLUETTE,
+	"|":  PIPE,
+	"^":  CARET,
+	"~":  TILDE,
+	"++": INCREMENT,
+	"--": DECREMENT,
+
+	// COMPARISON
+	"==": EQUAL,
+	"!=": NOT_EQUAL,
+	"<":  LESS,
+	"<=": LESS_EQUAL,
+	">":  GREATER,
+	">=": GREATER_EQUAL,
+
+	// ASSIGNMENT
+	"=":   ASSIGN,
+	"+=":  PLUS_ASSIGN,
+	"-=":  MINUS_ASSIGN,
+	"*=":  STAR_ASSIGN,
+	"/=":  SLASH_ASSIGN,
+	"%=":  PERCENT_ASSIGN,
+	"&=":  ESPERLUETTE_ASSIGN,
+	"|=":  PIPE_ASSIGN,
+	"^=":  CARET_ASSIGN,
+	"<<=": SHIFT_LEFT_ASSIGN,
+	">>=": SHIFT_RIGHT_ASSIGN,
+	"->":  ARROW,
+
+	// SHIFT
+	"<<": SHIFT_LEFT,
+	">>": SHIFT_RIGHT,
+
+	// LOGICAL
+	"&&": LOGICAL_AND,
+	"||": LOGICAL_OR,
+	"!":  LOGICAL_NOT,
+}
+
+// maxPunctuatorLength is the length of the longest punctuator spelling (%:%:).
+const maxPunctuatorLength = 4
+
+// Comment is a comment kept as trivia on the token next to it. Kind is either
+// SINGLE_LINE_COMMENT or MULTI_LINE_COMMENT and Text includes the delimiters.
+type Comment struct {
 	Kind  TokenKind
-	Value string
-	Line  int
-	Col   int
-	Index int
+	Text  string
+	Start source.Position
+	End   source.Position
+}
+
+type Token struct {
+	Kind     TokenKind
+	Value    string
+	Start    source.Position // position of the first byte of the token
+	End      source.Position // position just after the last byte of the token
+	Index    int
+	Leading  []Comment // comments between the previous line break and the token, or on the lines above it
+	Trailing []Comment // comments after the token on the same line
+
+	AtLineStart bool // first token of a logical line, line splices do not start a new one
+	HasSpace    bool // preceded by whitespace or a comment
 }
 
 func (t Token) IsOneOfMany(expectedTokens ...TokenKind) bool {
@@ -190,19 +290,19 @@ func (t Token) Debug(index ...int) {
 		fmt.Printf("% 3d: ", index[0])
 	}
 
-	if t.IsOneOfMany(INTEGER, UNSIGNED_INTEGER, FLOATING, CHARACTER, STRING, IDENTIFIER, INCLUDE_PATH, SINGLE_LINE_COMMENT, MULTI_LINE_COMMENT) {
-		fmt.Printf("%s (%s)\n", TokenKindString(t.Kind), t.Value)
+	if t.IsOneOfMany(INTEGER, UNSIGNED_INTEGER, FLOATING, CHARACTER, STRING, IDENTIFIER, INCLUDE_PATH, DIRECTIVE, INCLUDE_START, SINGLE_LINE_COMMENT, MULTI_LINE_COMMENT) {
+		fmt.Printf("%s (%s) at %s\n", TokenKindString(t.Kind), t.Value, t.Start)
 	} else {
-		fmt.Printf("%s ()\n", TokenKindString(t.Kind))
+		fmt.Printf("%s () at %s\n", TokenKindString(t.Kind), t.Start)
 	}
 }
 
-func NewToken(kind TokenKind, value string, line, col int) Token {
+func NewToken(kind TokenKind, value string, start, end source.Position) Token {
 	return Token{
 		Kind:  kind,
 		Value: value,
-		Line:  line,
-		Col:   col,
+		Start: start,
+		End:   end,
 	}
 }
 
@@ -236,10 +336,14 @@ func TokenKindString(token TokenKind) string {
 		return "COLON"
 	case DOT:
 		return "DOT"
+	case ELLIPSIS:
+		return "ELLIPSIS"
 	case QUESTION:
 		return "QUESTION"
 	case POUND:
 		return "POUND"
+	case POUND_POUND:
+		return "POUND_POUND"
 	case INTEGER:
 		return "INTEGER"
 	case UNSIGNED_INTEGER:
@@ -254,6 +358,12 @@ func TokenKindString(token TokenKind) string {
 		return "INCLUDER"
 	case INCLUDE_PATH:
 		return "INCLUDE_PATH"
+	case DIRECTIVE:
+		return "DIRECTIVE"
+	case INCLUDE_START:
+		return "INCLUDE_START"
+	case INCLUDE_END:
+		return "INCLUDE_END"
 	case PLUS:
 		return "PLUS"
 	case MINUS:
@@ -272,6 +382,10 @@ func TokenKindString(token TokenKind) string {
 		return "CARET"
 	case TILDE:
 		return "TILDE"
+	case INCREMENT:
+		return "INCREMENT"
+	case DECREMENT:
+		return "DECREMENT"
 	case EQUAL:
 		return "EQUAL"
 	case NOT_EQUAL:
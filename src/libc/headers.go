@@ -0,0 +1,360 @@
+package libc
+
+var headers = map[string]*Header{
+	"assert.h": {
+		Name:   "assert.h",
+		Macros: []Macro{{"static_assert", "_Static_assert"}},
+		Functions: []Function{
+			// assert is a macro, it is declared as a function to be resolved
+			fn("assert", "void", "int"),
+		},
+	},
+
+	"ctype.h": {
+		Name: "ctype.h",
+		Functions: []Function{
+			fn("isalnum", "int", "int"),
+			fn("isalpha", "int", "int"),
+			fn("isblank", "int", "int"),
+			fn("iscntrl", "int", "int"),
+			fn("isdigit", "int", "int"),
+			fn("isgraph", "int", "int"),
+			fn("islower", "int", "int"),
+			fn("isprint", "int", "int"),
+			fn("ispunct", "int", "int"),
+			fn("isspace", "int", "int"),
+			fn("isupper", "int", "int"),
+			fn("isxdigit", "int", "int"),
+			fn("tolower", "int", "int"),
+			fn("toupper", "int", "int"),
+		},
+	},
+
+	"errno.h": {
+		Name:      "errno.h",
+		Variables: []Variable{{"errno", "int"}},
+		Macros: []Macro{
+			{"EDOM", "33"},
+			{"EILSEQ", "84"},
+			{"ERANGE", "34"},
+		},
+	},
+
+	"limits.h": {
+		Name: "limits.h",
+		Macros: []Macro{
+			{"CHAR_BIT", "8"},
+			{"SCHAR_MIN", "(-128)"},
+			{"SCHAR_MAX", "127"},
+			{"UCHAR_MAX", "255"},
+			{"CHAR_MIN", "(-128)"},
+			{"CHAR_MAX", "127"},
+			{"MB_LEN_MAX", "16"},
+			{"SHRT_MIN", "(-32768)"},
+			{"SHRT_MAX", "32767"},
+			{"USHRT_MAX", "65535"},
+			{"INT_MIN", "(-2147483647-1)"},
+			{"INT_MAX", "2147483647"},
+			{"UINT_MAX", "4294967295U"},
+			{"LONG_MIN", "(-9223372036854775807L-1)"},
+			{"LONG_MAX", "9223372036854775807L"},
+			{"ULONG_MAX", "18446744073709551615UL"},
+			{"LLONG_MIN", "(-9223372036854775807LL-1)"},
+			{"LLONG_MAX", "9223372036854775807LL"},
+			{"ULLONG_MAX", "18446744073709551615ULL"},
+		},
+	},
+
+	"math.h": {
+		Name: "math.h",
+		Types: []Typedef{
+			{"float_t", "float"},
+			{"double_t", "double"},
+		},
+		Macros: []Macro{
+			{"HUGE_VAL", "(__builtin_huge_val())"},
+			{"INFINITY", "(__builtin_inff())"},
+			{"NAN", "(__builtin_nanf(\"\"))"},
+			{"M_PI", "3.14159265358979323846"},
+			{"M_E", "2.7182818284590452354"},
+		},
+		Functions: []Function{
+			fn("acos", "double", "double"),
+			fn("asin", "double", "double"),
+			fn("atan", "double", "double"),
+			fn("atan2", "double", "double", "double"),
+			fn("cos", "double", "double"),
+			fn("sin", "double", "double"),
+			fn("tan", "double", "double"),
+			fn("cosh", "double", "double"),
+			fn("sinh", "double", "double"),
+			fn("tanh", "double", "double"),
+			fn("exp", "double", "double"),
+			fn("exp2", "double", "double"),
+			fn("frexp", "double", "double", "int *"),
+			fn("ldexp", "double", "double", "int"),
+			fn("log", "double", "double"),
+			fn("log10", "double", "double"),
+			fn("log2", "double", "double"),
+			fn("modf", "double", "double", "double *"),
+			fn("pow", "double", "double", "double"),
+			fn("sqrt", "double", "double"),
+			fn("cbrt", "double", "double"),
+			fn("hypot", "double", "double", "double"),
+			fn("ceil", "double", "double"),
+			fn("floor", "double", "double"),
+			fn("round", "double", "double"),
+			fn("trunc", "double", "double"),
+			fn("fabs", "double", "double"),
+			fn("fmod", "double", "double", "double"),
+			fn("fmin", "double", "double", "double"),
+			fn("fmax", "double", "double", "double"),
+			fn("sqrtf", "float", "float"),
+			fn("powf", "float", "float", "float"),
+			fn("fabsf", "float", "float"),
+			fn("floorf", "float", "float"),
+			fn("ceilf", "float", "float"),
+			fn("roundf", "float", "float"),
+		},
+	},
+
+	"stdarg.h": {
+		Name:  "stdarg.h",
+		Types: []Typedef{{"va_list", "__builtin_va_list"}},
+		Functions: []Function{
+			// macros, declared as functions to be resolved
+			fn("va_start", "void", "va_list", "..."),
+			fn("va_arg", "void", "va_list", "..."),
+			fn("va_copy", "void", "va_list", "va_list"),
+			fn("va_end", "void", "va_list"),
+		},
+	},
+
+	"stdbool.h": {
+		Name:  "stdbool.h",
+		Types: []Typedef{{"bool", "_Bool"}},
+		Macros: []Macro{
+			{"true", "1"},
+			{"false", "0"},
+			{"__bool_true_false_are_defined", "1"},
+		},
+	},
+
+	"stddef.h": {
+		Name: "stddef.h",
+		Types: []Typedef{
+			{"size_t", "unsigned long"},
+			{"ptrdiff_t", "long"},
+			{"wchar_t", "int"},
+			{"max_align_t", "long double"},
+		},
+		Macros: []Macro{{"NULL", "((void *)0)"}},
+		Functions: []Function{
+			// offsetof is a macro taking a type, only its name is resolved
+			fn("offsetof", "size_t", "..."),
+		},
+	},
+
+	"stdint.h": {
+		Name: "stdint.h",
+		Types: []Typedef{
+			{"int8_t", "signed char"},
+			{"int16_t", "short"},
+			{"int32_t", "int"},
+			{"int64_t", "long"},
+			{"uint8_t", "unsigned char"},
+			{"uint16_t", "unsigned short"},
+			{"uint32_t", "unsigned int"},
+			{"uint64_t", "unsigned long"},
+			{"int_least8_t", "signed char"},
+			{"int_least16_t", "short"},
+			{"int_least32_t", "int"},
+			{"int_least64_t", "long"},
+			{"uint_least8_t", "unsigned char"},
+			{"uint_least16_t", "unsigned short"},
+			{"uint_least32_t", "unsigned int"},
+			{"uint_least64_t", "unsigned long"},
+			{"int_fast8_t", "signed char"},
+			{"int_fast16_t", "long"},
+			{"int_fast32_t", "long"},
+			{"int_fast64_t", "long"},
+			{"uint_fast8_t", "unsigned char"},
+			{"uint_fast16_t", "unsigned long"},
+			{"uint_fast32_t", "unsigned long"},
+			{"uint_fast64_t", "unsigned long"},
+			{"intptr_t", "long"},
+			{"uintptr_t", "unsigned long"},
+			{"intmax_t", "long"},
+			{"uintmax_t", "unsigned long"},
+		},
+		Macros: []Macro{
+			{"INT8_MIN", "(-128)"},
+			{"INT8_MAX", "127"},
+			{"UINT8_MAX", "255"},
+			{"INT16_MIN", "(-32768)"},
+			{"INT16_MAX", "32767"},
+			{"UINT16_MAX", "65535"},
+			{"INT32_MIN", "(-2147483647-1)"},
+			{"INT32_MAX", "2147483647"},
+			{"UINT32_MAX", "4294967295U"},
+			{"INT64_MIN", "(-9223372036854775807L-1)"},
+			{"INT64_MAX", "9223372036854775807L"},
+			{"UINT64_MAX", "18446744073709551615UL"},
+			{"INTPTR_MIN", "(-9223372036854775807L-1)"},
+			{"INTPTR_MAX", "9223372036854775807L"},
+			{"UINTPTR_MAX", "18446744073709551615UL"},
+			{"INTMAX_MIN", "(-9223372036854775807L-1)"},
+			{"INTMAX_MAX", "9223372036854775807L"},
+			{"UINTMAX_MAX", "18446744073709551615UL"},
+			{"SIZE_MAX", "18446744073709551615UL"},
+		},
+	},
+
+	"stdio.h": {
+		Name: "stdio.h",
+		Types: []Typedef{
+			{"FILE", "struct _IO_FILE"},
+			{"fpos_t", "long"},
+			{"size_t", "unsigned long"},
+		},
+		Variables: []Variable{
+			{"stdin", "FILE *"},
+			{"stdout", "FILE *"},
+			{"stderr", "FILE *"},
+		},
+		Macros: []Macro{
+			{"NULL", "((void *)0)"},
+			{"EOF", "(-1)"},
+			{"BUFSIZ", "8192"},
+			{"FILENAME_MAX", "4096"},
+			{"SEEK_SET", "0"},
+			{"SEEK_CUR", "1"},
+			{"SEEK_END", "2"},
+			{"_IOFBF", "0"},
+			{"_IOLBF", "1"},
+			{"_IONBF", "2"},
+		},
+		Functions: []Function{
+			fn("remove", "int", "const char *"),
+			fn("rename", "int", "const char *", "const char *"),
+			fn("tmpfile", "FILE *"),
+			fn("fclose", "int", "FILE *"),
+			fn("fflush", "int", "FILE *"),
+			fn("fopen", "FILE *", "const char *", "const char *"),
+			fn("freopen", "FILE *", "const char *", "const char *", "FILE *"),
+			fn("setbuf", "void", "FILE *", "char *"),
+			fn("setvbuf", "int", "FILE *", "char *", "int", "size_t"),
+			fn("fprintf", "int", "FILE *", "const char *", "..."),
+			fn("fscanf", "int", "FILE *", "const char *", "..."),
+			fn("printf", "int", "const char *", "..."),
+			fn("scanf", "int", "const char *", "..."),
+			fn("snprintf", "int", "char *", "size_t", "const char *", "..."),
+			fn("sprintf", "int", "char *", "const char *", "..."),
+			fn("sscanf", "int", "const char *", "const char *", "..."),
+			fn("vfprintf", "int", "FILE *", "const char *", "va_list"),
+			fn("vprintf", "int", "const char *", "va_list"),
+			fn("vsnprintf", "int", "char *", "size_t", "const char *", "va_list"),
+			fn("vsprintf", "int", "char *", "const char *", "va_list"),
+			fn("fgetc", "int", "FILE *"),
+			fn("fgets", "char *", "char *", "int", "FILE *"),
+			fn("fputc", "int", "int", "FILE *"),
+			fn("fputs", "int", "const char *", "FILE *"),
+			fn("getc", "int", "FILE *"),
+			fn("getchar", "int"),
+			fn("putc", "int", "int", "FILE *"),
+			fn("putchar", "int", "int"),
+			fn("puts", "int", "const char *"),
+			fn("ungetc", "int", "int", "FILE *"),
+			fn("fread", "size_t", "void *", "size_t", "size_t", "FILE *"),
+			fn("fwrite", "size_t", "const void *", "size_t", "size_t", "FILE *"),
+			fn("fgetpos", "int", "FILE *", "fpos_t *"),
+			fn("fseek", "int", "FILE *", "long", "int"),
+			fn("fsetpos", "int", "FILE *", "const fpos_t *"),
+			fn("ftell", "long", "FILE *"),
+			fn("rewind", "void", "FILE *"),
+			fn("clearerr", "void", "FILE *"),
+			fn("feof", "int", "FILE *"),
+			fn("ferror", "int", "FILE *"),
+			fn("perror", "void", "const char *"),
+		},
+	},
+
+	"stdlib.h": {
+		Name: "stdlib.h",
+		Types: []Typedef{
+			{"size_t", "unsigned long"},
+			{"div_t", "struct { int quot; int rem; }"},
+			{"ldiv_t", "struct { long quot; long rem; }"},
+		},
+		Macros: []Macro{
+			{"NULL", "((void *)0)"},
+			{"EXIT_FAILURE", "1"},
+			{"EXIT_SUCCESS", "0"},
+			{"RAND_MAX", "2147483647"},
+		},
+		Functions: []Function{
+			fn("atof", "double", "const char *"),
+			fn("atoi", "int", "const char *"),
+			fn("atol", "long", "const char *"),
+			fn("atoll", "long long", "const char *"),
+			fn("strtod", "double", "const char *", "char **"),
+			fn("strtof", "float", "const char *", "char **"),
+			fn("strtol", "long", "const char *", "char **", "int"),
+			fn("strtoll", "long long", "const char *", "char **", "int"),
+			fn("strtoul", "unsigned long", "const char *", "char **", "int"),
+			fn("strtoull", "unsigned long long", "const char *", "char **", "int"),
+			fn("rand", "int"),
+			fn("srand", "void", "unsigned int"),
+			fn("aligned_alloc", "void *", "size_t", "size_t"),
+			fn("calloc", "void *", "size_t", "size_t"),
+			fn("free", "void", "void *"),
+			fn("malloc", "void *", "size_t"),
+			fn("realloc", "void *", "void *", "size_t"),
+			fn("abort", "void"),
+			fn("atexit", "int", "void (*)(void)"),
+			fn("exit", "void", "int"),
+			fn("_Exit", "void", "int"),
+			fn("getenv", "char *", "const char *"),
+			fn("system", "int", "const char *"),
+			fn("bsearch", "void *", "const void *", "const void *", "size_t", "size_t", "int (*)(const void *, const void *)"),
+			fn("qsort", "void", "void *", "size_t", "size_t", "int (*)(const void *, const void *)"),
+			fn("abs", "int", "int"),
+			fn("labs", "long", "long"),
+			fn("llabs", "long long", "long long"),
+			fn("div", "div_t", "int", "int"),
+			fn("ldiv", "ldiv_t", "long", "long"),
+		},
+	},
+
+	"string.h": {
+		Name:   "string.h",
+		Types:  []Typedef{{"size_t", "unsigned long"}},
+		Macros: []Macro{{"NULL", "((void *)0)"}},
+		Functions: []Function{
+			fn("memcpy", "void *", "void *", "const void *", "size_t"),
+			fn("memmove", "void *", "void *", "const void *", "size_t"),
+			fn("memchr", "void *", "const void *", "int", "size_t"),
+			fn("memcmp", "int", "const void *", "const void *", "size_t"),
+			fn("memset", "void *", "void *", "int", "size_t"),
+			fn("strcpy", "char *", "char *", "const char *"),
+			fn("strncpy", "char *", "char *", "const char *", "size_t"),
+			fn("strcat", "char *", "char *", "const char *"),
+			fn("strncat", "char *", "char *", "const char *", "size_t"),
+			fn("strcmp", "int", "const char *", "const char *"),
+			fn("strncmp", "int", "const char *", "const char *", "size_t"),
+			fn("strcoll", "int", "const char *", "const char *"),
+			fn("strxfrm", "size_t", "char *", "const char *", "size_t"),
+			fn("strchr", "char *", "const char *", "int"),
+			fn("strrchr", "char *", "const char *", "int"),
+			fn("strcspn", "size_t", "const char *", "const char *"),
+			fn("strspn", "size_t", "const char *", "const char *"),
+			fn("strpbrk", "char *", "const char *", "const char *"),
+			fn("strstr", "char *", "const char *", "const char *"),
+			fn("strtok", "char *", "char *", "const char *"),
+			fn("strerror", "char *", "int"),
+			fn("strlen", "size_t", "const char *"),
+			fn("strdup", "char *", "const char *"),
+			fn("strndup", "char *", "const char *", "size_t"),
+		},
+	},
+}
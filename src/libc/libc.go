@@ -0,0 +1,75 @@
+// Package libc is a bundled database of the declarations of the C standard
+// library headers, used to resolve symbols when the headers of the toolchain
+// are not available.
+package libc
+
+import "sort"
+
+// Version of the declaration database, bumped whenever a declaration is
+// added, removed or changed.
+const Version = "1.0.0"
+
+// Standard is the revision of the C standard the declarations follow.
+const Standard = "C17"
+
+// Function is a function prototype, types are written as in C.
+type Function struct {
+	Name       string
+	Return     string
+	Params     []string
+	IsVariadic bool
+}
+
+// Typedef is a type name declared by a header.
+type Typedef struct {
+	Name string
+	Type string
+}
+
+// Variable is an object declared by a header, like stdout or errno.
+type Variable struct {
+	Name string
+	Type string
+}
+
+// Macro is an object-like macro defined by a header, like NULL or EOF.
+type Macro struct {
+	Name  string
+	Value string
+}
+
+// Header holds the declarations of a standard header.
+type Header struct {
+	Name      string // name between the angle brackets, like "stdio.h"
+	Functions []Function
+	Types     []Typedef
+	Variables []Variable
+	Macros    []Macro
+}
+
+// Lookup returns the declarations of a standard header, name being written
+// without delimiters ("stdio.h").
+func Lookup(name string) (*Header, bool) {
+	header, ok := headers[name]
+	return header, ok
+}
+
+// Headers returns the names of the known headers, sorted.
+func Headers() []string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fn builds a Function, a last parameter "..." makes it variadic.
+func fn(name, ret string, params ...string) Function {
+	f := Function{Name: name, Return: ret, Params: params}
+	if len(params) > 0 && params[len(params)-1] == "..." {
+		f.Params = params[:len(params)-1]
+		f.IsVariadic = true
+	}
+	return f
+}
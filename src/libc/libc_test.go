@@ -0,0 +1,85 @@
+package libc
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestHeaders checks that every header is found under its own name and
+// declares each name once.
+func TestHeaders(t *testing.T) {
+	names := Headers()
+	if !slices.IsSorted(names) {
+		t.Errorf("the header names are not sorted: %v", names)
+	}
+
+	for _, name := range names {
+		header, ok := Lookup(name)
+		if !ok {
+			t.Fatalf("%s is listed but not found", name)
+		}
+		if header.Name != name {
+			t.Errorf("%s is found under the name %s", header.Name, name)
+		}
+
+		declared := map[string]bool{}
+		declare := func(symbol string) {
+			if declared[symbol] {
+				t.Errorf("%s declares %s twice", name, symbol)
+			}
+			declared[symbol] = true
+		}
+
+		for _, function := range header.Functions {
+			declare(function.Name)
+			if slices.Contains(function.Params, "...") {
+				t.Errorf("%s: %s has ... in its parameters instead of being variadic", name, function.Name)
+			}
+		}
+		for _, typedef := range header.Types {
+			declare(typedef.Name)
+		}
+		for _, variable := range header.Variables {
+			declare(variable.Name)
+		}
+		for _, macro := range header.Macros {
+			declare(macro.Name)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		header string
+		symbol string
+	}{
+		{"stdio.h", "printf"},
+		{"stdio.h", "FILE"},
+		{"stdio.h", "stdout"},
+		{"stdio.h", "EOF"},
+		{"stdlib.h", "malloc"},
+		{"string.h", "strlen"},
+		{"string.h", "NULL"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.header+"/"+test.symbol, func(t *testing.T) {
+			header, ok := Lookup(test.header)
+			if !ok {
+				t.Fatalf("%s not found", test.header)
+			}
+
+			found := slices.ContainsFunc(header.Functions, func(f Function) bool { return f.Name == test.symbol }) ||
+				slices.ContainsFunc(header.Types, func(typedef Typedef) bool { return typedef.Name == test.symbol }) ||
+				slices.ContainsFunc(header.Variables, func(v Variable) bool { return v.Name == test.symbol }) ||
+				slices.ContainsFunc(header.Macros, func(m Macro) bool { return m.Name == test.symbol })
+			if !found {
+				t.Errorf("%s does not declare %s", test.header, test.symbol)
+			}
+		})
+	}
+
+	if _, ok := Lookup("<stdio.h>"); ok {
+		t.Error("a name with delimiters was found")
+	}
+}
@@ -4,23 +4,30 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/ZiplEix/c_parser/src/lexer"
-	"github.com/ZiplEix/c_parser/src/parser"
+	"github.com/ZiplEix/c_parser/src/cplus"
+	"github.com/ZiplEix/c_parser/src/diagnostic"
 	"github.com/sanity-io/litter"
 )
 
 func main() {
-	bytes, err := os.ReadFile("main.c")
+	name := "main.c"
+	if len(os.Args) > 1 {
+		name = os.Args[1]
+	}
+
+	bytes, err := os.ReadFile(name)
 	if err != nil {
 		panic(err)
 	}
 
-	tokens := lexer.Tokensize(string(bytes))
+	options := cplus.Options{KeepTrivia: true}
 
 	fmt.Printf("------\n")
 	fmt.Printf("TOKENS\n")
 	fmt.Printf("------\n")
 
+	// the diagnostics of the tokens are reported by ParseFile
+	tokens, _ := cplus.Tokenize(name, bytes, options)
 	for index, token := range tokens {
 		token.Debug(index)
 	}
@@ -29,6 +36,14 @@ func main() {
 	fmt.Printf("AST\n")
 	fmt.Printf("------\n")
 
-	ast := parser.Parse(tokens)
+	ast, diagnostics := cplus.ParseFile(name, bytes, options)
 	litter.Dump(ast)
+
+	for _, d := range diagnostics {
+		fmt.Fprintln(os.Stderr, d.Error())
+	}
+
+	if diagnostic.HasErrors(diagnostics) {
+		os.Exit(1)
+	}
 }
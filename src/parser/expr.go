@@ -1,31 +1,39 @@
 package parser
 
 import (
-	"fmt"
+	"math"
+	"math/bits"
 	"strconv"
+	"strings"
 
 	"github.com/ZiplEix/c_parser/src/ast"
+	"github.com/ZiplEix/c_parser/src/diagnostic"
 	"github.com/ZiplEix/c_parser/src/lexer"
 )
 
 func parse_expr(p *parser, bp binding_power) ast.Expr {
 	// First parse the nud
 	tokenKind := p.currentTokenKind()
-	nud_fn, exists := nud_lu[tokenKind]
+	nud_fn, exists := p.lookups.nud_lu[tokenKind]
 
 	if !exists {
-		panic(fmt.Sprintf("NUD HANDLER EXPECTED FOR TOKEN '%s' at index %d\n", lexer.TokenKindString(tokenKind), p.currentToken().Index))
+		token := p.currentToken()
+		p.report(diagnostic.Errorf(diagnostic.PARSE_EXPECTED_EXPR, p.tokenSpan(token), "expected expression but got %s", lexer.TokenKindString(tokenKind)))
+
+		return ast.BadExpr{
+			Span: ast.Span{From: token.Start, To: token.Start},
+		}
 	}
 
 	left := nud_fn(p)
 
 	// while we have a led and the current bp is < bp of current token
 	// continue parsing the left hand side
-	for bp_lu[p.currentTokenKind()] > bp {
+	for p.lookups.bp_lu[p.currentTokenKind()] > bp {
 		tokenKind := p.currentTokenKind()
-		led_fn, exists := led_lu[tokenKind]
+		led_fn, exists := p.lookups.led_lu[tokenKind]
 		if !exists {
-			panic(fmt.Sprintf("LED HANDLER EXPECTED FOR TOKEN '%s'\n", lexer.TokenKindString(tokenKind)))
+			p.fail(diagnostic.Errorf(diagnostic.PARSE_UNEXPECTED_TOKEN, p.tokenSpan(p.currentToken()), "unexpected %s in expression", lexer.TokenKindString(tokenKind)))
 		}
 
 		left = led_fn(p, left, bp)
@@ -34,56 +42,309 @@ func parse_expr(p *parser, bp binding_power) ast.Expr {
 	return left
 }
 
+// tokenNodeSpan returns the span of a node made of a single token.
+func tokenNodeSpan(token lexer.Token) ast.Span {
+	return ast.Span{From: token.Start, To: token.End}
+}
+
 func parse_primary_expr(p *parser) ast.Expr {
 	switch p.currentTokenKind() {
 	case lexer.CHARACTER:
-		return ast.CharacterExpr{Value: p.advance().Value}
-	case lexer.INTEGER:
-		number, _ := strconv.ParseInt(p.advance().Value, 10, 64)
-		return ast.IntegerExpr{Value: number}
+		token := p.advance()
+		// invalid constants were reported by the lexer
+		literal, _ := lexer.DecodeChar(token.Value)
+		return ast.CharacterExpr{Span: tokenNodeSpan(token), Value: literal.Value, Raw: token.Value, Prefix: literal.Prefix}
+	case lexer.INTEGER, lexer.UNSIGNED_INTEGER:
+		return parse_integer_literal(p, p.advance())
 	case lexer.FLOATING:
-		number, _ := strconv.ParseFloat(p.advance().Value, 64)
-		return ast.FloatExpr{Value: number}
-	case lexer.UNSIGNED_INTEGER:
-		number, _ := strconv.ParseUint(p.advance().Value, 10, 64)
-		return ast.UnsignedIntegerExpr{Value: number}
+		return parse_float_literal(p, p.advance())
 	case lexer.STRING:
-		return ast.StringExpr{Value: p.advance().Value}
+		return parse_string_literal(p)
 	case lexer.IDENTIFIER:
-		return ast.SymbolExpr{Value: p.advance().Value}
+		symbol := p.advance()
+		return ast.SymbolExpr{Span: tokenNodeSpan(symbol), Value: symbol.Value}
 	default:
-		panic(fmt.Sprintf("Cannot create primary_expression from %s \n", lexer.TokenKindString(p.currentTokenKind())))
+		p.fail(diagnostic.Errorf(diagnostic.PARSE_EXPECTED_EXPR, p.tokenSpan(p.currentToken()), "cannot create primary expression from %s", lexer.TokenKindString(p.currentTokenKind())))
+		return nil
+	}
+}
+
+// parse_string_literal parses a sequence of adjacent string literals, which are
+// concatenated into a single one (translation phase 6). A piece without
+// encoding prefix takes the prefix of the others.
+func parse_string_literal(p *parser) ast.Expr {
+	start := p.currentToken()
+	parts := []ast.StringPart{}
+	prefix := ""
+	var prefixToken lexer.Token
+
+	for p.currentTokenKind() == lexer.STRING {
+		token := p.advance()
+		// invalid literals were reported by the lexer
+		literal, _ := lexer.DecodeString(token.Value)
+
+		if literal.Prefix != "" && prefix != "" && literal.Prefix != prefix {
+			p.report(diagnostic.Errorf(diagnostic.PARSE_STRING_CONCAT, p.tokenSpan(token), "concatenation of string literals with conflicting encoding prefixes %s and %s", prefix, literal.Prefix).
+				WithNote(p.tokenSpan(prefixToken), "previous prefix is here"))
+		} else if literal.Prefix != "" && prefix == "" {
+			prefix = literal.Prefix
+			prefixToken = token
+		}
+
+		parts = append(parts, ast.StringPart{
+			Raw:    token.Value,
+			Prefix: literal.Prefix,
+			Start:  token.Start,
+			End:    token.End,
+		})
+	}
+
+	value := ""
+	raws := make([]string, 0, len(parts))
+
+	for _, part := range parts {
+		spelling := part.Raw
+		if part.Prefix == "" {
+			spelling = prefix + spelling
+		}
+
+		literal, _ := lexer.DecodeString(spelling)
+		value += literal.Value
+		raws = append(raws, part.Raw)
+	}
+
+	return ast.StringExpr{
+		Span:   p.spanFrom(start),
+		Value:  value,
+		Raw:    strings.Join(raws, " "),
+		Prefix: prefix,
+		Parts:  parts,
+	}
+}
+
+// integerCandidate is one of the types an integer constant can take, in the
+// order of C23 6.4.4.1.
+type integerCandidate struct {
+	varType    ast.VarType
+	isUnsigned bool
+	bits       uint
+}
+
+var (
+	int_candidate                = integerCandidate{ast.INT, false, 31}
+	unsigned_int_candidate       = integerCandidate{ast.INT, true, 32}
+	long_candidate               = integerCandidate{ast.LONG, false, 63}
+	unsigned_long_candidate      = integerCandidate{ast.LONG, true, 64}
+	long_long_candidate          = integerCandidate{ast.LONG_LONG, false, 63}
+	unsigned_long_long_candidate = integerCandidate{ast.LONG_LONG, true, 64}
+)
+
+// integerCandidates returns the list of types an integer constant may have,
+// the first one able to represent the value is its type.
+func integerCandidates(literal lexer.IntegerLiteral) []integerCandidate {
+	decimal := literal.Base == 10
+
+	switch {
+	case literal.IsUnsigned && literal.LongCount == 0:
+		return []integerCandidate{unsigned_int_candidate, unsigned_long_candidate, unsigned_long_long_candidate}
+	case literal.IsUnsigned && literal.LongCount == 1:
+		return []integerCandidate{unsigned_long_candidate, unsigned_long_long_candidate}
+	case literal.IsUnsigned:
+		return []integerCandidate{unsigned_long_long_candidate}
+	case literal.LongCount == 0 && decimal:
+		return []integerCandidate{int_candidate, long_candidate, long_long_candidate}
+	case literal.LongCount == 0:
+		return []integerCandidate{int_candidate, unsigned_int_candidate, long_candidate, unsigned_long_candidate, long_long_candidate, unsigned_long_long_candidate}
+	case literal.LongCount == 1 && decimal:
+		return []integerCandidate{long_candidate, long_long_candidate}
+	case literal.LongCount == 1:
+		return []integerCandidate{long_candidate, unsigned_long_candidate, long_long_candidate, unsigned_long_long_candidate}
+	case decimal:
+		return []integerCandidate{long_long_candidate}
+	default:
+		return []integerCandidate{long_long_candidate, unsigned_long_long_candidate}
+	}
+}
+
+func parse_integer_literal(p *parser, token lexer.Token) ast.Expr {
+	literal, err := lexer.DecodeInteger(token.Value)
+	if err != nil {
+		// already reported by the lexer
+		return ast.IntegerExpr{Span: tokenNodeSpan(token), Raw: token.Value, Base: literal.Base, Suffix: literal.Suffix, Type: ast.INT}
+	}
+
+	value, err := strconv.ParseUint(literal.Digits, literal.Base, 64)
+	if err != nil {
+		p.report(diagnostic.Errorf(diagnostic.PARSE_INTEGER_OVERFLOW, p.tokenSpan(token), "integer constant '%s' is too large for any integer type", token.Value))
+		value = math.MaxUint64
+	}
+
+	if literal.BitPrecise {
+		if literal.IsUnsigned {
+			return ast.UnsignedIntegerExpr{Span: tokenNodeSpan(token), Value: value, Raw: token.Value, Base: literal.Base, Suffix: literal.Suffix, Type: ast.BIT_INT}
+		}
+		return ast.IntegerExpr{Span: tokenNodeSpan(token), Value: int64(value), Raw: token.Value, Base: literal.Base, Suffix: literal.Suffix, Type: ast.BIT_INT}
+	}
+
+	candidates := integerCandidates(literal)
+	chosen := candidates[len(candidates)-1]
+	fits := false
+
+	for _, candidate := range candidates {
+		if bits.Len64(value) <= int(candidate.bits) {
+			chosen = candidate
+			fits = true
+			break
+		}
+	}
+
+	// only decimal constants without u suffix can end up here, gcc and clang
+	// make them unsigned long long
+	if !fits {
+		if err == nil {
+			p.report(diagnostic.Warningf(diagnostic.PARSE_INTEGER_OVERFLOW, p.tokenSpan(token), "integer constant '%s' is so large that it is unsigned", token.Value))
+		}
+		chosen = unsigned_long_long_candidate
+	}
+
+	if chosen.isUnsigned {
+		return ast.UnsignedIntegerExpr{Span: tokenNodeSpan(token), Value: value, Raw: token.Value, Base: literal.Base, Suffix: literal.Suffix, Type: chosen.varType}
+	}
+
+	return ast.IntegerExpr{Span: tokenNodeSpan(token), Value: int64(value), Raw: token.Value, Base: literal.Base, Suffix: literal.Suffix, Type: chosen.varType}
+}
+
+func parse_float_literal(p *parser, token lexer.Token) ast.Expr {
+	literal, err := lexer.DecodeFloat(token.Value)
+	if err != nil {
+		// already reported by the lexer
+		return ast.FloatExpr{Span: tokenNodeSpan(token), Raw: token.Value, Type: ast.DOUBLE}
+	}
+
+	varType := ast.DOUBLE
+	bitSize := 64
+
+	switch literal.Suffix {
+	case "f", "F":
+		varType = ast.FLOAT
+		bitSize = 32
+	case "l", "L":
+		varType = ast.LONG_DOUBLE
 	}
+
+	value, err := strconv.ParseFloat(literal.Number, bitSize)
+	if err != nil {
+		p.report(diagnostic.Warningf(diagnostic.PARSE_FLOAT_RANGE, p.tokenSpan(token), "floating constant '%s' exceeds the range of its type", token.Value))
+	}
+
+	return ast.FloatExpr{Span: tokenNodeSpan(token), Value: value, Raw: token.Value, Type: varType}
 }
 
-func parse_binary_expr(p *parser, left ast.Expr, bp binding_power) ast.Expr {
+func parse_binary_expr(p *parser, left ast.Expr, _ binding_power) ast.Expr {
 	operatorToken := p.advance()
-	right := parse_expr(p, bp)
+	// the right operand only takes the operators binding tighter, which makes
+	// the binary operators left associative
+	right := parse_expr(p, p.lookups.bp_lu[operatorToken.Kind])
 
 	return ast.BinaryExpr{
+		Span:     ast.Span{From: left.Pos(), To: right.End()},
 		Left:     left,
 		Operator: operatorToken,
 		Right:    right,
 	}
 }
 
-// func parse_call_expr(p *parser, left ast.Expr, _ binding_power) ast.Expr {
-// 	p.expect(lexer.LPAREN) // consume the LPAREN
+func parse_assignment_expr(p *parser, left ast.Expr, _ binding_power) ast.Expr {
+	operatorToken := p.advance()
+	// parsing the value one level below makes `a = b = c` be `a = (b = c)`
+	value := parse_expr(p, assignment-1)
 
-// 	args := []ast.Expr{}
+	return ast.AssignmentExpr{
+		Span:     ast.Span{From: left.Pos(), To: value.End()},
+		Assignee: left,
+		Operator: operatorToken,
+		Value:    value,
+	}
+}
+
+func parse_prefix_expr(p *parser) ast.Expr {
+	operatorToken := p.advance()
+	operand := parse_expr(p, unary)
 
-// 	for p.currentTokenKind() != lexer.RPAREN {
-// 		args = append(args, parse_expr(p, default_bp))
+	return ast.UnaryExpr{
+		Span:     ast.Span{From: operatorToken.Start, To: operand.End()},
+		Operator: operatorToken,
+		Operand:  operand,
+	}
+}
 
-// 		if p.currentTokenKind() == lexer.COMMA {
-// 			p.advance()
-// 		}
-// 	}
+func parse_postfix_expr(p *parser, left ast.Expr, _ binding_power) ast.Expr {
+	operatorToken := p.advance()
+
+	return ast.PostfixExpr{
+		Span:     ast.Span{From: left.Pos(), To: operatorToken.End},
+		Operand:  left,
+		Operator: operatorToken,
+	}
+}
 
-// 	p.expect(lexer.RPAREN)
+func parse_call_expr(p *parser, left ast.Expr, _ binding_power) ast.Expr {
+	p.expect(lexer.LPAREN)
 
-// 	return ast.CallExpr{
-// 		Func: left,
-// 		Args: args,
-// 	}
-// }
+	args := []ast.Expr{}
+
+	if p.currentTokenKind() != lexer.RPAREN {
+		for {
+			// the arguments bind tighter than a comma operator
+			args = append(args, parse_expr(p, comma))
+
+			if p.currentTokenKind() != lexer.COMMA {
+				break
+			}
+			p.advance()
+		}
+	}
+
+	p.expect(lexer.RPAREN)
+
+	return ast.CallExpr{
+		Span: ast.Span{From: left.Pos(), To: p.lastEnd},
+		Func: left,
+		Args: args,
+	}
+}
+
+func parse_member_expr(p *parser, left ast.Expr, _ binding_power) ast.Expr {
+	operatorToken := p.advance()
+	member := p.expect(lexer.IDENTIFIER)
+
+	return ast.MemberExpr{
+		Span:     ast.Span{From: left.Pos(), To: member.End},
+		X:        left,
+		Operator: operatorToken,
+		Member:   member.Value,
+	}
+}
+
+func parse_index_expr(p *parser, left ast.Expr, _ binding_power) ast.Expr {
+	p.expect(lexer.LBRACKET)
+	index := parse_expr(p, default_bp)
+	p.expect(lexer.RBRACKET)
+
+	return ast.IndexExpr{
+		Span:  ast.Span{From: left.Pos(), To: p.lastEnd},
+		X:     left,
+		Index: index,
+	}
+}
+
+func parse_grouping_expr(p *parser) ast.Expr {
+	start := p.expect(lexer.LPAREN)
+	expr := parse_expr(p, default_bp)
+	p.expect(lexer.RPAREN)
+
+	return ast.ParenExpr{
+		Span: p.spanFrom(start),
+		X:    expr,
+	}
+}
@@ -0,0 +1,305 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ZiplEix/c_parser/src/ast"
+	"github.com/ZiplEix/c_parser/src/diagnostic"
+	"github.com/ZiplEix/c_parser/src/lexer"
+)
+
+// format writes an expression with every operation between parentheses, so
+// the precedence and the associativity chosen by the parser are visible.
+func format(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case ast.IntegerExpr:
+		return e.Raw
+	case ast.UnsignedIntegerExpr:
+		return e.Raw
+	case ast.FloatExpr:
+		return e.Raw
+	case ast.CharacterExpr:
+		return e.Raw
+	case ast.StringExpr:
+		return e.Raw
+	case ast.SymbolExpr:
+		return e.Value
+	case ast.ParenExpr:
+		return "(" + format(e.X) + ")"
+	case ast.BinaryExpr:
+		return fmt.Sprintf("(%s %s %s)", format(e.Left), e.Operator.Value, format(e.Right))
+	case ast.AssignmentExpr:
+		return fmt.Sprintf("(%s %s %s)", format(e.Assignee), e.Operator.Value, format(e.Value))
+	case ast.UnaryExpr:
+		return fmt.Sprintf("(%s%s)", e.Operator.Value, format(e.Operand))
+	case ast.PostfixExpr:
+		return fmt.Sprintf("(%s%s)", format(e.Operand), e.Operator.Value)
+	case ast.CallExpr:
+		args := make([]string, len(e.Args))
+		for i, arg := range e.Args {
+			args[i] = format(arg)
+		}
+		return fmt.Sprintf("%s(%s)", format(e.Func), strings.Join(args, ", "))
+	case ast.MemberExpr:
+		return fmt.Sprintf("(%s%s%s)", format(e.X), e.Operator.Value, e.Member)
+	case ast.IndexExpr:
+		return fmt.Sprintf("(%s[%s])", format(e.X), format(e.Index))
+	default:
+		return fmt.Sprintf("%T", expr)
+	}
+}
+
+// parseExprStmt parses the source as a single expression statement, which
+// must have no diagnostics.
+func parseExprStmt(t *testing.T, source string) ast.Expr {
+	t.Helper()
+
+	expr, diagnostics := parseExprDiagnostics(t, source)
+	if len(diagnostics) > 0 {
+		t.Fatalf("unexpected diagnostics: %v", diagnostics)
+	}
+	return expr
+}
+
+// parseExprDiagnostics parses the source as a single expression statement,
+// and returns it with the diagnostics of the lexer and of the parser.
+func parseExprDiagnostics(t *testing.T, source string) (ast.Expr, []diagnostic.Diagnostic) {
+	t.Helper()
+
+	tokens, diagnostics := lexer.Tokensize(source + ";")
+	block, parseDiagnostics := Parse(tokens)
+	diagnostics = append(diagnostics, parseDiagnostics...)
+
+	if len(block.Body) != 1 {
+		t.Fatalf("got %d statements, want 1", len(block.Body))
+	}
+
+	stmt, ok := block.Body[0].(*ast.ExprStmt)
+	if !ok {
+		t.Fatalf("got %T, want *ast.ExprStmt", block.Body[0])
+	}
+	return stmt.Expr, diagnostics
+}
+
+// severities returns the severity and code of each diagnostic, like
+// "warning[P0004]".
+func severities(diagnostics []diagnostic.Diagnostic) []string {
+	result := []string{}
+	for _, d := range diagnostics {
+		result = append(result, fmt.Sprintf("%s[%s]", d.Severity, d.Code))
+	}
+	return result
+}
+
+func TestParseExprPrecedence(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		// one operator on each side of another, from the loosest to the tightest
+		{"a || b && c", "(a || (b && c))"},
+		{"a && b || c", "((a && b) || c)"},
+		{"a && b | c", "(a && (b | c))"},
+		{"a | b ^ c", "(a | (b ^ c))"},
+		{"a ^ b & c", "(a ^ (b & c))"},
+		{"a & b == c", "(a & (b == c))"},
+		{"a == b < c", "(a == (b < c))"},
+		{"a < b == c", "((a < b) == c)"},
+		{"a < b << c", "(a < (b << c))"},
+		{"a << b + c", "(a << (b + c))"},
+		{"a + b * c", "(a + (b * c))"},
+		{"a * b + c", "((a * b) + c)"},
+		{"a | b & c ^ d", "(a | ((b & c) ^ d))"},
+		{"a != b >= c >> 1", "(a != (b >= (c >> 1)))"},
+
+		// left associativity of the operators sharing a level
+		{"a - b - c", "((a - b) - c)"},
+		{"a / b * c", "((a / b) * c)"},
+		{"a << b >> c", "((a << b) >> c)"},
+		{"a < b > c", "((a < b) > c)"},
+		{"a == b != c", "((a == b) != c)"},
+		{"a & b & c", "((a & b) & c)"},
+		{"a || b || c", "((a || b) || c)"},
+
+		// unary and postfix operators
+		{"-a * b", "((-a) * b)"},
+		{"!a && b", "((!a) && b)"},
+		{"*p++", "(*(p++))"},
+		{"&p", "(&p)"},
+		{"~a & b", "((~a) & b)"},
+		{"a - -b", "(a - (-b))"},
+		{"- - a", "(-(-a))"},
+
+		// parentheses and calls
+		{"(a + b) * c", "(((a + b)) * c)"},
+		{"f(a + b, c) * d", "(f((a + b), c) * d)"},
+
+		// assignments are right associative and bind the loosest
+		{"a = b || c", "(a = (b || c))"},
+		{"a += b << 1", "(a += (b << 1))"},
+		{"a = b = c", "(a = (b = c))"},
+		{"p.x = x", "((p.x) = x)"},
+
+		// members and subscripts
+		{"p->x.y", "((p->x).y)"},
+		{"a[i][j]", "((a[i])[j])"},
+		{"a[i + 1] * 2", "((a[(i + 1)]) * 2)"},
+		{"*p->x", "(*(p->x))"},
+		{"&p.x", "(&(p.x))"},
+		{"-a[0]", "(-(a[0]))"},
+		{"p->x++", "((p->x)++)"},
+		{"f(x).y", "(f(x).y)"},
+		{"s.f(x)", "(s.f)(x)"},
+		{"t[i](x)", "(t[i])(x)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			if got := format(parseExprStmt(t, test.source)); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseIntegerType(t *testing.T) {
+	tests := []struct {
+		source      string
+		want        ast.Expr
+		diagnostics []string
+	}{
+		{"0", ast.IntegerExpr{Value: 0, Base: 10, Type: ast.INT}, nil},
+		{"2147483647", ast.IntegerExpr{Value: 2147483647, Base: 10, Type: ast.INT}, nil},
+		{"2147483648", ast.IntegerExpr{Value: 2147483648, Base: 10, Type: ast.LONG}, nil},
+		{"4294967295", ast.IntegerExpr{Value: 4294967295, Base: 10, Type: ast.LONG}, nil},
+		{"0x7FFFFFFF", ast.IntegerExpr{Value: 0x7FFFFFFF, Base: 16, Type: ast.INT}, nil},
+		{"0xFFFFFFFF", ast.UnsignedIntegerExpr{Value: 0xFFFFFFFF, Base: 16, Type: ast.INT}, nil},
+		{"0x100000000", ast.IntegerExpr{Value: 0x100000000, Base: 16, Type: ast.LONG}, nil},
+		{"0xFFFFFFFFFFFFFFFF", ast.UnsignedIntegerExpr{Value: 0xFFFFFFFFFFFFFFFF, Base: 16, Type: ast.LONG}, nil},
+		{"037777777777", ast.UnsignedIntegerExpr{Value: 0xFFFFFFFF, Base: 8, Type: ast.INT}, nil},
+		{"0b101", ast.IntegerExpr{Value: 5, Base: 2, Type: ast.INT}, nil},
+		{"1'000'000", ast.IntegerExpr{Value: 1000000, Base: 10, Type: ast.INT}, nil},
+		{"1u", ast.UnsignedIntegerExpr{Value: 1, Base: 10, Suffix: "u", Type: ast.INT}, nil},
+		{"4294967296u", ast.UnsignedIntegerExpr{Value: 4294967296, Base: 10, Suffix: "u", Type: ast.LONG}, nil},
+		{"1l", ast.IntegerExpr{Value: 1, Base: 10, Suffix: "l", Type: ast.LONG}, nil},
+		{"1ul", ast.UnsignedIntegerExpr{Value: 1, Base: 10, Suffix: "ul", Type: ast.LONG}, nil},
+		{"1LL", ast.IntegerExpr{Value: 1, Base: 10, Suffix: "LL", Type: ast.LONG_LONG}, nil},
+		{"0xFFFFFFFFFFFFFFFFll", ast.UnsignedIntegerExpr{Value: 0xFFFFFFFFFFFFFFFF, Base: 16, Suffix: "ll", Type: ast.LONG_LONG}, nil},
+		{"10wb", ast.IntegerExpr{Value: 10, Base: 10, Suffix: "wb", Type: ast.BIT_INT}, nil},
+		{"10uwb", ast.UnsignedIntegerExpr{Value: 10, Base: 10, Suffix: "uwb", Type: ast.BIT_INT}, nil},
+		{
+			"9223372036854775808",
+			ast.UnsignedIntegerExpr{Value: 9223372036854775808, Base: 10, Type: ast.LONG_LONG},
+			[]string{"warning[P0004]"},
+		},
+		{
+			"18446744073709551616",
+			ast.UnsignedIntegerExpr{Value: 18446744073709551615, Base: 10, Type: ast.LONG_LONG},
+			[]string{"error[P0004]"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			expr, diagnostics := parseExprDiagnostics(t, test.source)
+
+			if got := severities(diagnostics); !slices.Equal(got, test.diagnostics) {
+				t.Errorf("got diagnostics %v, want %v", got, test.diagnostics)
+			}
+
+			// the span and the spelling are not part of the expectations
+			switch e := expr.(type) {
+			case ast.IntegerExpr:
+				e.Span, e.Raw = ast.Span{}, ""
+				expr = e
+			case ast.UnsignedIntegerExpr:
+				e.Span, e.Raw = ast.Span{}, ""
+				expr = e
+			}
+			if expr != test.want {
+				t.Errorf("got %#v, want %#v", expr, test.want)
+			}
+		})
+	}
+}
+
+func TestParseFloatValue(t *testing.T) {
+	tests := []struct {
+		source      string
+		want        ast.FloatExpr
+		diagnostics []string
+	}{
+		{"1.5", ast.FloatExpr{Value: 1.5, Type: ast.DOUBLE}, nil},
+		{"1.5f", ast.FloatExpr{Value: 1.5, Type: ast.FLOAT}, nil},
+		{"1.5L", ast.FloatExpr{Value: 1.5, Type: ast.LONG_DOUBLE}, nil},
+		{"2.5e-3", ast.FloatExpr{Value: 2.5e-3, Type: ast.DOUBLE}, nil},
+		{"1'000.5", ast.FloatExpr{Value: 1000.5, Type: ast.DOUBLE}, nil},
+		{"0x1.8p3", ast.FloatExpr{Value: 12, Type: ast.DOUBLE}, nil},
+		{"0x1p-2f", ast.FloatExpr{Value: 0.25, Type: ast.FLOAT}, nil},
+		{"0xA.8P0", ast.FloatExpr{Value: 10.5, Type: ast.DOUBLE}, nil},
+		{"1e400", ast.FloatExpr{Value: math.Inf(1), Type: ast.DOUBLE}, []string{"warning[P0005]"}},
+		{"1e39f", ast.FloatExpr{Value: math.Inf(1), Type: ast.FLOAT}, []string{"warning[P0005]"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			expr, diagnostics := parseExprDiagnostics(t, test.source)
+
+			if got := severities(diagnostics); !slices.Equal(got, test.diagnostics) {
+				t.Errorf("got diagnostics %v, want %v", got, test.diagnostics)
+			}
+
+			got, ok := expr.(ast.FloatExpr)
+			if !ok {
+				t.Fatalf("got %T, want ast.FloatExpr", expr)
+			}
+			if got.Value != test.want.Value || got.Type != test.want.Type {
+				t.Errorf("got %v of type %d, want %v of type %d", got.Value, got.Type, test.want.Value, test.want.Type)
+			}
+		})
+	}
+}
+
+func TestParseCallExpr(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"f()", "f()"},
+		{"f(a)", "f(a)"},
+		{"f(a, b + c, -d)", "f(a, (b + c), (-d))"},
+		{"f(g(a), h(b, i()))", "f(g(a), h(b, i()))"},
+		{"f(a)(b)(c)", "f(a)(b)(c)"},
+		{"(*fp)(a)", "((*fp))(a)"},
+		{"(f)(a)", "(f)(a)"},
+		{"fp[i](a)", "(fp[i])(a)"},
+		{"s->handler(s, 1)", "(s->handler)(s, 1)"},
+		{"f(x = 1, y)", "f((x = 1), y)"},
+		{"*f(a)", "(*f(a))"},
+		{"!f(a) && g(b)", "((!f(a)) && g(b))"},
+		{"f(a)++", "(f(a)++)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			if got := format(parseExprStmt(t, test.source)); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseCallExprErrors(t *testing.T) {
+	for _, source := range []string{"f(a,)", "f(,a)", "f(a b)", "f(a"} {
+		t.Run(source, func(t *testing.T) {
+			tokens, _ := lexer.Tokensize(source + ";")
+			if _, diagnostics := Parse(tokens); len(diagnostics) == 0 {
+				t.Error("got no diagnostics")
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"sync"
+
 	"github.com/ZiplEix/c_parser/src/ast"
 	"github.com/ZiplEix/c_parser/src/lexer"
 )
@@ -11,8 +13,14 @@ const (
 	default_bp binding_power = iota
 	comma
 	assignment
-	logical
+	logical_or
+	logical_and
+	bitwise_or
+	bitwise_xor
+	bitwise_and
+	equality
 	relational
+	shift
 	additive
 	multiplicative
 	unary
@@ -30,13 +38,29 @@ type nud_lookup map[lexer.TokenKind]nud_handler
 type led_lookup map[lexer.TokenKind]led_handler
 type bp_lookup map[lexer.TokenKind]binding_power
 
+// lookup_tables holds the grammar of the Pratt parser. The tables are built
+// once by tokenLookup and only read afterwards, so they are shared by all the
+// parsers, including the ones running concurrently.
+type lookup_tables struct {
+	bp_lu   bp_lookup
+	nud_lu  nud_lookup
+	led_lu  led_lookup
+	stmt_lu stmt_lookup
+}
+
 var (
-	bp_lu   = bp_lookup{}
-	nud_lu  = nud_lookup{}
-	led_lu  = led_lookup{}
-	stmt_lu = stmt_lookup{}
+	lookupOnce sync.Once
+	lookups    *lookup_tables
 )
 
+// tokenLookup returns the grammar tables, building them on the first call.
+func tokenLookup() *lookup_tables {
+	lookupOnce.Do(func() {
+		lookups = createTokenLookup()
+	})
+	return lookups
+}
+
 // led (Left Denotation) est utilisé pour les jetons qui apparaissent au
 // milieu ou à la fin d'une expression (comme les opérateurs binaires), et
 // cette fonction est responsable de l'analyse de ces opérations.
@@ -44,9 +68,9 @@ var (
 // Par exemple, lorsqu'un opérateur binaire comme "+", "-", "*", etc., est
 // rencontré, ces cas sont gérés par des fonctions spécifiques définies dans
 // led_lu.
-func led(kind lexer.TokenKind, bp binding_power, led_fn led_handler) {
-	bp_lu[kind] = bp
-	led_lu[kind] = led_fn
+func (t *lookup_tables) led(kind lexer.TokenKind, bp binding_power, led_fn led_handler) {
+	t.bp_lu[kind] = bp
+	t.led_lu[kind] = led_fn
 }
 
 // nud (Null Denotation) est utilisé pour les jetons qui peuvent apparaître au
@@ -56,62 +80,127 @@ func led(kind lexer.TokenKind, bp binding_power, led_fn led_handler) {
 // Par exemple, une expression peut commencer par un nombre, une chaîne ou un
 // identifiant, et ces cas sont gérés par des fonctions spécifiques définies
 // dans nud_lu.
-func nud(kind lexer.TokenKind, _ binding_power, nud_fn nud_handler) {
-	bp_lu[kind] = primary
-	nud_lu[kind] = nud_fn
+func (t *lookup_tables) nud(kind lexer.TokenKind, _ binding_power, nud_fn nud_handler) {
+	// a token that is also an operator keeps the binding power of its led
+	if _, isLed := t.led_lu[kind]; !isLed {
+		t.bp_lu[kind] = primary
+	}
+	t.nud_lu[kind] = nud_fn
 }
 
-func stmt(kind lexer.TokenKind, stmt_fn stmt_handler) {
-	bp_lu[kind] = default_bp
-	stmt_lu[kind] = stmt_fn
+func (t *lookup_tables) stmt(kind lexer.TokenKind, stmt_fn stmt_handler) {
+	t.bp_lu[kind] = default_bp
+	t.stmt_lu[kind] = stmt_fn
 }
 
-func createTokenLookup() {
-	// Logical
-	led(lexer.LOGICAL_AND, logical, parse_binary_expr)
-	led(lexer.LOGICAL_OR, logical, parse_binary_expr)
+func createTokenLookup() *lookup_tables {
+	t := &lookup_tables{
+		bp_lu:   bp_lookup{},
+		nud_lu:  nud_lookup{},
+		led_lu:  led_lookup{},
+		stmt_lu: stmt_lookup{},
+	}
+
+	// Assignment
+	t.led(lexer.ASSIGN, assignment, parse_assignment_expr)
+	t.led(lexer.PLUS_ASSIGN, assignment, parse_assignment_expr)
+	t.led(lexer.MINUS_ASSIGN, assignment, parse_assignment_expr)
+	t.led(lexer.STAR_ASSIGN, assignment, parse_assignment_expr)
+	t.led(lexer.SLASH_ASSIGN, assignment, parse_assignment_expr)
+	t.led(lexer.PERCENT_ASSIGN, assignment, parse_assignment_expr)
+	t.led(lexer.ESPERLUETTE_ASSIGN, assignment, parse_assignment_expr)
+	t.led(lexer.PIPE_ASSIGN, assignment, parse_assignment_expr)
+	t.led(lexer.CARET_ASSIGN, assignment, parse_assignment_expr)
+	t.led(lexer.SHIFT_LEFT_ASSIGN, assignment, parse_assignment_expr)
+	t.led(lexer.SHIFT_RIGHT_ASSIGN, assignment, parse_assignment_expr)
 
-	// Relational
-	led(lexer.EQUAL, relational, parse_binary_expr)
-	led(lexer.NOT_EQUAL, relational, parse_binary_expr)
-	led(lexer.LESS, relational, parse_binary_expr)
-	led(lexer.LESS_EQUAL, relational, parse_binary_expr)
-	led(lexer.GREATER, relational, parse_binary_expr)
-	led(lexer.GREATER_EQUAL, relational, parse_binary_expr)
+	// Logical
+	t.led(lexer.LOGICAL_OR, logical_or, parse_binary_expr)
+	t.led(lexer.LOGICAL_AND, logical_and, parse_binary_expr)
+
+	// Bitwise
+	t.led(lexer.PIPE, bitwise_or, parse_binary_expr)
+	t.led(lexer.CARET, bitwise_xor, parse_binary_expr)
+	t.led(lexer.ESPERLUETTE, bitwise_and, parse_binary_expr)
+
+	// Equality & Relational
+	t.led(lexer.EQUAL, equality, parse_binary_expr)
+	t.led(lexer.NOT_EQUAL, equality, parse_binary_expr)
+	t.led(lexer.LESS, relational, parse_binary_expr)
+	t.led(lexer.LESS_EQUAL, relational, parse_binary_expr)
+	t.led(lexer.GREATER, relational, parse_binary_expr)
+	t.led(lexer.GREATER_EQUAL, relational, parse_binary_expr)
+
+	// Shift
+	t.led(lexer.SHIFT_LEFT, shift, parse_binary_expr)
+	t.led(lexer.SHIFT_RIGHT, shift, parse_binary_expr)
 
 	// Additive & Multiplicative
-	led(lexer.PLUS, additive, parse_binary_expr)
-	led(lexer.MINUS, additive, parse_binary_expr)
-	led(lexer.STAR, multiplicative, parse_binary_expr)
-	led(lexer.SLASH, multiplicative, parse_binary_expr)
-	led(lexer.PERCENT, multiplicative, parse_binary_expr)
+	t.led(lexer.PLUS, additive, parse_binary_expr)
+	t.led(lexer.MINUS, additive, parse_binary_expr)
+	t.led(lexer.STAR, multiplicative, parse_binary_expr)
+	t.led(lexer.SLASH, multiplicative, parse_binary_expr)
+	t.led(lexer.PERCENT, multiplicative, parse_binary_expr)
 
 	// literals & symbols
-	nud(lexer.INTEGER, primary, parse_primary_expr)
-	nud(lexer.FLOATING, primary, parse_primary_expr)
-	nud(lexer.CHARACTER, primary, parse_primary_expr)
-	nud(lexer.STRING, primary, parse_primary_expr)
-	nud(lexer.IDENTIFIER, primary, parse_primary_expr)
-
-	// Computed / Call
-	// led(lexer.LPAREN, call, parse_call_expr)
+	t.nud(lexer.INTEGER, primary, parse_primary_expr)
+	t.nud(lexer.UNSIGNED_INTEGER, primary, parse_primary_expr)
+	t.nud(lexer.FLOATING, primary, parse_primary_expr)
+	t.nud(lexer.CHARACTER, primary, parse_primary_expr)
+	t.nud(lexer.STRING, primary, parse_primary_expr)
+	t.nud(lexer.IDENTIFIER, primary, parse_primary_expr)
+
+	// Unary / Prefix
+	t.nud(lexer.MINUS, unary, parse_prefix_expr)
+	t.nud(lexer.PLUS, unary, parse_prefix_expr)
+	t.nud(lexer.LOGICAL_NOT, unary, parse_prefix_expr)
+	t.nud(lexer.TILDE, unary, parse_prefix_expr)
+	t.nud(lexer.STAR, unary, parse_prefix_expr)
+	t.nud(lexer.ESPERLUETTE, unary, parse_prefix_expr)
+	t.nud(lexer.INCREMENT, unary, parse_prefix_expr)
+	t.nud(lexer.DECREMENT, unary, parse_prefix_expr)
+
+	// Grouping / Call / Postfix
+	t.nud(lexer.LPAREN, primary, parse_grouping_expr)
+	t.led(lexer.LPAREN, call, parse_call_expr)
+	t.led(lexer.INCREMENT, call, parse_postfix_expr)
+	t.led(lexer.DECREMENT, call, parse_postfix_expr)
+
+	// Member / Subscript
+	t.led(lexer.DOT, member, parse_member_expr)
+	t.led(lexer.ARROW, member, parse_member_expr)
+	t.led(lexer.LBRACKET, member, parse_index_expr)
 
 	// Statements
-	stmt(lexer.MULTI_LINE_COMMENT, parse_comment_stmt)
-	stmt(lexer.SINGLE_LINE_COMMENT, parse_comment_stmt)
-
-	stmt(lexer.INCLUDER, parse_includer_stmt)
-
-	stmt(lexer.RETURN, parse_return_stmt)
-
-	stmt(lexer.VOID, parse_var_declaration_stmt)
-	stmt(lexer.CHAR, parse_var_declaration_stmt)
-	stmt(lexer.SHORT, parse_var_declaration_stmt)
-	stmt(lexer.INT, parse_var_declaration_stmt)
-	stmt(lexer.LONG, parse_var_declaration_stmt)
-	stmt(lexer.FLOAT, parse_var_declaration_stmt)
-	stmt(lexer.DOUBLE, parse_var_declaration_stmt)
-	stmt(lexer.SIGNED, parse_var_declaration_stmt)
-	stmt(lexer.UNSIGNED, parse_var_declaration_stmt)
-	stmt(lexer.CONST, parse_var_declaration_stmt)
+	t.stmt(lexer.INCLUDER, parse_includer_stmt)
+	t.stmt(lexer.DIRECTIVE, parse_directive_stmt)
+
+	t.stmt(lexer.LBRACE, parse_block_stmt)
+	t.stmt(lexer.SEMICOLON, parse_empty_stmt)
+
+	t.stmt(lexer.RETURN, parse_return_stmt)
+	t.stmt(lexer.IF, parse_if_stmt)
+	t.stmt(lexer.WHILE, parse_while_stmt)
+	t.stmt(lexer.DO, parse_do_while_stmt)
+	t.stmt(lexer.FOR, parse_for_stmt)
+	t.stmt(lexer.BREAK, parse_break_stmt)
+	t.stmt(lexer.CONTINUE, parse_continue_stmt)
+	t.stmt(lexer.SWITCH, parse_switch_stmt)
+	t.stmt(lexer.CASE, parse_case_clause)
+	t.stmt(lexer.DEFAULT, parse_case_clause)
+	t.stmt(lexer.GOTO, parse_goto_stmt)
+	t.stmt(lexer.TYPEDEF, parse_typedef_stmt)
+
+	t.stmt(lexer.VOID, parse_var_declaration_stmt)
+	t.stmt(lexer.CHAR, parse_var_declaration_stmt)
+	t.stmt(lexer.SHORT, parse_var_declaration_stmt)
+	t.stmt(lexer.INT, parse_var_declaration_stmt)
+	t.stmt(lexer.LONG, parse_var_declaration_stmt)
+	t.stmt(lexer.FLOAT, parse_var_declaration_stmt)
+	t.stmt(lexer.DOUBLE, parse_var_declaration_stmt)
+	t.stmt(lexer.SIGNED, parse_var_declaration_stmt)
+	t.stmt(lexer.UNSIGNED, parse_var_declaration_stmt)
+	t.stmt(lexer.CONST, parse_var_declaration_stmt)
+
+	return t
 }
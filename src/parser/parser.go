@@ -4,34 +4,75 @@ import (
 	"fmt"
 
 	"github.com/ZiplEix/c_parser/src/ast"
+	"github.com/ZiplEix/c_parser/src/diagnostic"
 	"github.com/ZiplEix/c_parser/src/lexer"
+	"github.com/ZiplEix/c_parser/src/source"
 )
 
 type parser struct {
-	tokens []lexer.Token
-	pos    int
+	lookups     *lookup_tables
+	source      lexer.TokenSource
+	current     lexer.Token
+	next        *lexer.Token // token after current, once peeked
+	pos         int          // number of tokens consumed
+	lastEnd     source.Position
+	lastError   source.Position
+	diagnostics []diagnostic.Diagnostic
+	trivia      []*triviaFrame
+	typeNames   map[string]bool // names usable as a type, declared by typedef or a standard header
+	switchDepth int             // number of switch statements being parsed
 }
 
-func createParser(tokens []lexer.Token) *parser {
-	createTokenLookup()
+// triviaFrame collects the comments of the tokens consumed by the statement
+// being parsed.
+type triviaFrame struct {
+	started      bool
+	trivia       ast.Trivia
+	lastTrailing []lexer.Comment
+}
+
+// bailout is used as a panic value to unwind the parser once an error has
+// been reported. It never escapes the package.
+type bailout struct{}
+
+func createParser(source lexer.TokenSource) *parser {
 	return &parser{
-		tokens: tokens,
-		pos:    0,
+		lookups:   tokenLookup(),
+		source:    source,
+		current:   source.Next(),
+		pos:       0,
+		typeNames: map[string]bool{},
 	}
 }
 
-func Parse(tokens []lexer.Token) ast.BlockStmt {
+// Parse builds the AST of the given tokens. Statements containing a syntax
+// error are replaced by an ast.BadStmt and the parsing resumes after them, so
+// every error of the file is returned in the diagnostics.
+func Parse(tokens []lexer.Token) (*ast.BlockStmt, []diagnostic.Diagnostic) {
+	return ParseSource(lexer.NewSliceSource(tokens))
+}
+
+// ParseSource is like Parse, the tokens being read one at a time from source,
+// like a lexer.Scanner or a preprocessor.Preprocessor.
+func ParseSource(source lexer.TokenSource) (*ast.BlockStmt, []diagnostic.Diagnostic) {
 	body := make([]ast.Stmt, 0)
 
-	p := createParser(tokens)
+	p := createParser(source)
+	start := p.currentToken()
 
 	for p.hasTokens() {
 		body = append(body, parseStmt(p))
 	}
 
-	return ast.BlockStmt{
-		Body: body,
-	}
+	// comments after the last statement are attached to the file itself
+	var trivia ast.Trivia
+	trivia.Inner = p.currentToken().Leading
+
+	return &ast.BlockStmt{
+		Span:   ast.Span{From: start.Start, To: p.currentToken().End},
+		Trivia: trivia,
+		Body:   body,
+	}, p.diagnostics
 }
 
 //
@@ -39,21 +80,131 @@ func Parse(tokens []lexer.Token) ast.BlockStmt {
 //
 
 func (p *parser) currentToken() lexer.Token {
-	return p.tokens[p.pos]
+	return p.current
 }
 
 func (p *parser) currentTokenKind() lexer.TokenKind {
-	return p.tokens[p.pos].Kind
+	return p.current.Kind
+}
+
+// peek returns the token following the current one without consuming it.
+func (p *parser) peek() lexer.Token {
+	if p.current.Kind == lexer.EOF {
+		return p.current
+	}
+
+	if p.next == nil {
+		next := p.source.Next()
+		p.next = &next
+	}
+	return *p.next
 }
 
 func (p *parser) advance() lexer.Token {
 	tk := p.currentToken()
+	p.lastEnd = tk.End
 	p.pos++
+	if p.next != nil {
+		p.current = *p.next
+		p.next = nil
+	} else if tk.Kind != lexer.EOF {
+		p.current = p.source.Next()
+	}
+
+	if len(p.trivia) > 0 {
+		frame := p.trivia[len(p.trivia)-1]
+		if !frame.started {
+			frame.started = true
+			frame.trivia.Leading = tk.Leading
+		} else {
+			frame.trivia.Inner = append(frame.trivia.Inner, frame.lastTrailing...)
+			frame.trivia.Inner = append(frame.trivia.Inner, tk.Leading...)
+		}
+		frame.lastTrailing = tk.Trailing
+	}
+
 	return tk
 }
 
+// openTrivia starts collecting the comments of a new statement.
+func (p *parser) openTrivia() {
+	p.trivia = append(p.trivia, &triviaFrame{})
+}
+
+// closeTrivia returns the comments collected since the matching openTrivia.
+// The enclosing statement will not see them.
+func (p *parser) closeTrivia() ast.Trivia {
+	frame := p.trivia[len(p.trivia)-1]
+	p.trivia = p.trivia[:len(p.trivia)-1]
+	frame.trivia.Trailing = frame.lastTrailing
+
+	if len(p.trivia) > 0 && frame.started {
+		parent := p.trivia[len(p.trivia)-1]
+		if parent.started {
+			parent.trivia.Inner = append(parent.trivia.Inner, parent.lastTrailing...)
+		}
+		parent.started = true
+		parent.lastTrailing = nil
+	}
+
+	return frame.trivia
+}
+
+// isTypeName reports whether the token is a name declared by typedef, or by
+// one of the standard headers included.
+func (p *parser) isTypeName(token lexer.Token) bool {
+	return token.Kind == lexer.IDENTIFIER && p.typeNames[token.Value]
+}
+
 func (p *parser) hasTokens() bool {
-	return p.pos < len(p.tokens) && p.currentTokenKind() != lexer.EOF
+	return p.currentTokenKind() != lexer.EOF
+}
+
+// report records a diagnostic without interrupting the parsing. Only the
+// first error reported at a given position is kept, errors following it at
+// the same place are usually consequences of the first one.
+func (p *parser) report(d diagnostic.Diagnostic) {
+	if d.Severity == diagnostic.ERROR {
+		if p.lastError.IsValid() && p.lastError.Offset == d.Span.Start.Offset {
+			return
+		}
+		p.lastError = d.Span.Start
+	}
+
+	p.diagnostics = append(p.diagnostics, d)
+}
+
+// fail records the diagnostic and unwinds the parser.
+func (p *parser) fail(d diagnostic.Diagnostic) {
+	p.report(d)
+	panic(bailout{})
+}
+
+// synchronize skips tokens until a point where a new statement is likely to
+// start: after a ';', before a '}', before a type keyword or at the end of an
+// included header.
+func (p *parser) synchronize() {
+	for p.hasTokens() {
+		switch kind := p.currentTokenKind(); {
+		case kind == lexer.SEMICOLON:
+			p.advance()
+			return
+		case kind == lexer.RBRACE, kind == lexer.INCLUDE_END, isType(kind):
+			return
+		}
+
+		p.advance()
+	}
+}
+
+// spanFrom returns the span of the node starting with the given token and
+// ending with the last token consumed.
+func (p *parser) spanFrom(start lexer.Token) ast.Span {
+	return ast.Span{From: start.Start, To: p.lastEnd}
+}
+
+func (p *parser) tokenSpan(token lexer.Token) diagnostic.Span {
+	return diagnostic.Span{Start: token.Start, End: token.End}
 }
 
 func (p *parser) expectError(expectedKind lexer.TokenKind, err any) lexer.Token {
@@ -62,11 +213,15 @@ func (p *parser) expectError(expectedKind lexer.TokenKind, err any) lexer.Token
 
 	if kind != expectedKind {
 		if err == nil {
-			err = fmt.Sprintf("Expected %s but got %s\n", lexer.TokenKindString(expectedKind), lexer.TokenKindString(kind))
-			panic(err)
+			err = fmt.Sprintf("expected %s but got %s", lexer.TokenKindString(expectedKind), lexer.TokenKindString(kind))
+		}
+
+		d := diagnostic.Errorf(diagnostic.PARSE_UNEXPECTED_TOKEN, p.tokenSpan(token), "%v", err)
+		if expectedKind == lexer.SEMICOLON && p.lastEnd.IsValid() {
+			d = d.WithFixIt(diagnostic.Span{Start: p.lastEnd, End: p.lastEnd}, ";")
 		}
 
-		panic(err)
+		p.fail(d)
 	}
 
 	return p.advance()
@@ -0,0 +1,53 @@
+package parser
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/ZiplEix/c_parser/src/lexer"
+)
+
+// TestParseParallel parses many files at the same time, the lookup tables
+// being shared by all the parsers. Run it with -race.
+func TestParseParallel(t *testing.T) {
+	const files = 200
+
+	var wg sync.WaitGroup
+	errors := make(chan string, files)
+
+	for i := 0; i < files; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			source := fmt.Sprintf(`typedef unsigned int u%d;
+int square_%d(int x) { return x * x; }
+int main(void) {
+	u%d n = %d;
+	for (int i = 0; i < n; i++) {
+		if (i %% 2 == 0) continue;
+		n -= square_%d(i) + -1;
+	}
+	return n;
+}
+`, i, i, i, i, i)
+
+			tokens, _ := lexer.TokensizeFile(fmt.Sprintf("file_%d.c", i), source)
+			block, diagnostics := Parse(tokens)
+
+			if len(diagnostics) > 0 {
+				errors <- fmt.Sprintf("file %d: unexpected diagnostics %v", i, diagnostics)
+			} else if len(block.Body) != 3 {
+				errors <- fmt.Sprintf("file %d: got %d statements, want 3", i, len(block.Body))
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	close(errors)
+
+	for err := range errors {
+		t.Error(err)
+	}
+}
@@ -1,12 +1,56 @@
 package parser
 
 import (
+	"strings"
+	"unicode"
+
 	"github.com/ZiplEix/c_parser/src/ast"
+	"github.com/ZiplEix/c_parser/src/diagnostic"
 	"github.com/ZiplEix/c_parser/src/lexer"
+	"github.com/ZiplEix/c_parser/src/libc"
 )
 
-func parseStmt(p *parser) ast.Stmt {
-	stmt_fn, exist := stmt_lu[p.currentTokenKind()]
+// parseStmt parses a single statement and attaches the comments around it. If
+// an error is reported while parsing it, the parser is resynchronized and an
+// ast.BadStmt covering the skipped tokens is returned instead.
+func parseStmt(p *parser) (stmt ast.Stmt) {
+	startPos := p.pos
+	startToken := p.currentToken()
+	p.openTrivia()
+
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(bailout); !ok {
+				panic(r)
+			}
+
+			p.synchronize()
+			if p.pos == startPos && p.hasTokens() {
+				p.advance()
+			}
+
+			stmt = &ast.BadStmt{
+				Span: p.spanFrom(startToken),
+			}
+		}
+
+		trivia := p.closeTrivia()
+		if holder, ok := stmt.(interface{ SetTrivia(ast.Trivia) }); ok {
+			holder.SetTrivia(trivia)
+		}
+	}()
+
+	// a label, like `cleanup:`
+	if p.currentTokenKind() == lexer.IDENTIFIER && p.peek().Kind == lexer.COLON {
+		return parse_labeled_stmt(p)
+	}
+
+	// a declaration starting with a typedef name, like `size_t n;`
+	if p.isTypeName(p.currentToken()) {
+		return parse_var_declaration_stmt(p)
+	}
+
+	stmt_fn, exist := p.lookups.stmt_lu[p.currentTokenKind()]
 
 	if exist {
 		return stmt_fn(p)
@@ -16,12 +60,13 @@ func parseStmt(p *parser) ast.Stmt {
 	p.expect(lexer.SEMICOLON)
 
 	return &ast.ExprStmt{
+		Span: p.spanFrom(startToken),
 		Expr: expression,
 	}
 }
 
 func parse_block_stmt(p *parser) ast.Stmt {
-	p.expect(lexer.LBRACE)
+	start := p.expect(lexer.LBRACE)
 	body := []ast.Stmt{}
 
 	for p.hasTokens() && p.currentTokenKind() != lexer.RBRACE {
@@ -30,7 +75,8 @@ func parse_block_stmt(p *parser) ast.Stmt {
 
 	p.expect(lexer.RBRACE)
 
-	return ast.BlockStmt{
+	return &ast.BlockStmt{
+		Span: p.spanFrom(start),
 		Body: body,
 	}
 }
@@ -45,44 +91,74 @@ func isType(tokenKind lexer.TokenKind) bool {
 }
 
 func parse_var_declaration_stmt(p *parser) ast.Stmt {
-	isConst, isSigned, pointerLevel, varType, varName := parse_var_type_and_name(p)
+	start := p.currentToken()
+	isConst, isSigned, pointerLevel, varType, typeName, varName := parse_var_type_and_name(p)
 
 	// var declaration without assigment
 	if p.currentTokenKind() == lexer.SEMICOLON {
 		p.advance()
 		return &ast.VarDeclarationStmt{
+			Span:         p.spanFrom(start),
 			Name:         varName,
 			IsConst:      isConst,
 			IsSigned:     isSigned,
 			PointerLevel: pointerLevel,
 			Type:         varType,
+			TypeName:     typeName,
 		}
 	} else if p.currentToken().Kind == lexer.LPAREN {
 		// function declaration
-		return parse_func_declaration_stmt(p, varName, isConst, isSigned, pointerLevel, varType)
+		return parse_func_declaration_stmt(p, start, varName, isConst, isSigned, pointerLevel, varType, typeName)
 	}
 
 	p.expect(lexer.ASSIGN)
-	assignedExpr := parse_expr(p, assignment)
+	// an initializer is an assignment expression, but not a comma one
+	assignedExpr := parse_expr(p, comma)
 	p.expect(lexer.SEMICOLON)
 
 	return &ast.VarDeclarationStmt{
+		Span:         p.spanFrom(start),
 		Name:         varName,
 		IsConst:      isConst,
 		IsSigned:     isSigned,
 		PointerLevel: pointerLevel,
 		Type:         varType,
+		TypeName:     typeName,
 		AssignedExpr: assignedExpr,
 	}
 }
 
-func parse_var_type_and_name(p *parser) (bool, bool, int, ast.VarType, string) {
+func parse_var_type_and_name(p *parser) (bool, bool, int, ast.VarType, string, string) {
+	isConst, isSigned, pointerLevel, varType, typeName := parse_var_type(p)
+	varName := p.expect(lexer.IDENTIFIER).Value
+
+	return isConst, isSigned, pointerLevel, varType, typeName, varName
+}
+
+// parse_var_type parses the type of a declaration. typeName is set when the
+// type is a typedef name, varType being ast.TYPE_NAME.
+func parse_var_type(p *parser) (bool, bool, int, ast.VarType, string) {
 	isConst := false
 	isSigned := true
 	pointerLevel := 0
 	varType := ast.INT
+	typeName := ""
+	hasType := false
+
+	for {
+		// a typedef name is only the type if no other type was given, in
+		// `size_t size_t;` the second one is the declared name
+		if !hasType && p.isTypeName(p.currentToken()) {
+			varType = ast.TYPE_NAME
+			typeName = p.advance().Value
+			hasType = true
+			continue
+		}
+
+		if !isType(p.currentTokenKind()) && p.currentTokenKind() != lexer.CONST && p.currentTokenKind() != lexer.SIGNED && p.currentTokenKind() != lexer.UNSIGNED && p.currentTokenKind() != lexer.STAR {
+			break
+		}
 
-	for isType(p.currentTokenKind()) || p.currentTokenKind() == lexer.CONST || p.currentTokenKind() == lexer.SIGNED || p.currentTokenKind() == lexer.UNSIGNED || p.currentTokenKind() == lexer.STAR {
 		if p.currentTokenKind() == lexer.CONST {
 			isConst = true
 		}
@@ -97,6 +173,7 @@ func parse_var_type_and_name(p *parser) (bool, bool, int, ast.VarType, string) {
 		}
 
 		if isType(p.currentTokenKind()) {
+			hasType = true
 			switch p.currentTokenKind() {
 			case lexer.VOID:
 				varType = ast.VOID
@@ -114,93 +191,436 @@ func parse_var_type_and_name(p *parser) (bool, bool, int, ast.VarType, string) {
 		p.advance()
 	}
 
-	varName := p.expect(lexer.IDENTIFIER).Value
-
-	return isConst, isSigned, pointerLevel, varType, varName
+	return isConst, isSigned, pointerLevel, varType, typeName
 }
 
-func parse_function_param_and_body(p *parser) ([]ast.Parameter, []ast.Stmt) {
+// parse_function_param_and_body parses the parameter list of a function and
+// its body. The body is nil for a prototype, ended by a ';'.
+func parse_function_param_and_body(p *parser) ([]ast.Parameter, bool, []ast.Stmt) {
 	functionParameters := make([]ast.Parameter, 0)
+	isVariadic := false
 
 	p.expect(lexer.LPAREN)
 	for p.hasTokens() && p.currentTokenKind() != lexer.RPAREN {
-		isConst, isSigned, pointerLevel, varType, varName := parse_var_type_and_name(p)
+		if p.currentTokenKind() == lexer.ELLIPSIS {
+			p.advance()
+			isVariadic = true
+			break
+		}
+
+		paramStart := p.currentToken()
+		isConst, isSigned, pointerLevel, varType, typeName := parse_var_type(p)
+
+		// parameter names are optional in prototypes
+		varName := ""
+		if p.currentTokenKind() == lexer.IDENTIFIER {
+			varName = p.advance().Value
+		}
 
-		currentParameter := ast.Parameter{
+		functionParameters = append(functionParameters, ast.Parameter{
+			Span:         p.spanFrom(paramStart),
 			Name:         varName,
 			IsConst:      isConst,
 			IsSigned:     isSigned,
 			PointerLevel: pointerLevel,
 			Type:         varType,
-		}
-
-		// fmt.Printf("Current parameter:\n")
-		// litter.Dump(currentParameter)
-
-		functionParameters = append(functionParameters, currentParameter)
+			TypeName:     typeName,
+		})
 
-		if !p.currentToken().IsOneOfMany(lexer.COMMA, lexer.RPAREN) {
+		if p.currentTokenKind() != lexer.RPAREN {
 			p.expect(lexer.COMMA)
 		}
+	}
 
-		if p.currentTokenKind() == lexer.RPAREN {
-			break
+	p.expect(lexer.RPAREN)
+
+	// `(void)` is an empty parameter list
+	if len(functionParameters) == 1 && !isVariadic {
+		param := functionParameters[0]
+		if param.Type == ast.VOID && param.PointerLevel == 0 && param.Name == "" {
+			functionParameters = functionParameters[:0]
 		}
+	}
 
+	if p.currentTokenKind() == lexer.SEMICOLON {
 		p.advance()
+		return functionParameters, isVariadic, nil
 	}
 
-	p.expect(lexer.RPAREN)
-
-	functionBody := ast.ExpectStmt[ast.BlockStmt](parse_block_stmt(p)).Body
+	blockStart := p.currentToken()
+	block, err := ast.ExpectStmt[*ast.BlockStmt](parse_block_stmt(p))
+	if err != nil {
+		p.fail(diagnostic.Errorf(diagnostic.PARSE_UNEXPECTED_NODE, p.tokenSpan(blockStart), "invalid function body: %s", err))
+	}
 
-	return functionParameters, functionBody
+	return functionParameters, isVariadic, block.Body
 }
 
-func parse_func_declaration_stmt(p *parser, functionName string, isConst, isSigned bool, pointerLevel int, returnType ast.VarType) ast.Stmt {
-	functionParameters, functionBody := parse_function_param_and_body(p)
+func parse_func_declaration_stmt(p *parser, start lexer.Token, functionName string, isConst, isSigned bool, pointerLevel int, returnType ast.VarType, typeName string) ast.Stmt {
+	functionParameters, isVariadic, functionBody := parse_function_param_and_body(p)
 
 	return &ast.FunctionDeclarationStmt{
+		Span:         p.spanFrom(start),
 		Name:         functionName,
 		IsConst:      isConst,
 		IsSigned:     isSigned,
 		PointerLevel: pointerLevel,
 		ReturnType:   returnType,
+		TypeName:     typeName,
 		Parameters:   functionParameters,
+		IsVariadic:   isVariadic,
 		Body:         functionBody,
 	}
 }
 
+func parse_empty_stmt(p *parser) ast.Stmt {
+	start := p.expect(lexer.SEMICOLON)
+
+	return &ast.EmptyStmt{
+		Span: p.spanFrom(start),
+	}
+}
+
 func parse_return_stmt(p *parser) ast.Stmt {
-	p.advance()
+	start := p.advance()
 
-	expr := parse_expr(p, default_bp)
+	// `return;` has no value
+	var expr ast.Expr
+	if p.currentTokenKind() != lexer.SEMICOLON {
+		expr = parse_expr(p, default_bp)
+	}
 	p.expect(lexer.SEMICOLON)
 
 	return &ast.ReturnStmt{
+		Span: p.spanFrom(start),
 		Expr: expr,
 	}
 }
 
-func parse_comment_stmt(p *parser) ast.Stmt {
-	var kind ast.VarType
+func parse_if_stmt(p *parser) ast.Stmt {
+	start := p.expect(lexer.IF)
 
-	if p.currentTokenKind() == lexer.MULTI_LINE_COMMENT {
-		kind = ast.MULTI_LINE_COMMENT
+	p.expect(lexer.LPAREN)
+	cond := parse_expr(p, default_bp)
+	p.expect(lexer.RPAREN)
+
+	then := parseStmt(p)
+
+	// an else belongs to the nearest if
+	var elseStmt ast.Stmt
+	if p.currentTokenKind() == lexer.ELSE {
+		p.advance()
+		elseStmt = parseStmt(p)
+	}
+
+	return &ast.IfStmt{
+		Span: p.spanFrom(start),
+		Cond: cond,
+		Then: then,
+		Else: elseStmt,
+	}
+}
+
+func parse_while_stmt(p *parser) ast.Stmt {
+	start := p.expect(lexer.WHILE)
+
+	p.expect(lexer.LPAREN)
+	cond := parse_expr(p, default_bp)
+	p.expect(lexer.RPAREN)
+
+	body := parseStmt(p)
+
+	return &ast.WhileStmt{
+		Span: p.spanFrom(start),
+		Cond: cond,
+		Body: body,
+	}
+}
+
+func parse_do_while_stmt(p *parser) ast.Stmt {
+	start := p.expect(lexer.DO)
+
+	body := parseStmt(p)
+
+	p.expect(lexer.WHILE)
+	p.expect(lexer.LPAREN)
+	cond := parse_expr(p, default_bp)
+	p.expect(lexer.RPAREN)
+	p.expect(lexer.SEMICOLON)
+
+	return &ast.DoWhileStmt{
+		Span: p.spanFrom(start),
+		Body: body,
+		Cond: cond,
+	}
+}
+
+func parse_for_stmt(p *parser) ast.Stmt {
+	start := p.expect(lexer.FOR)
+	p.expect(lexer.LPAREN)
+
+	// the init clause is a declaration or an expression, both ended by a ';'
+	var init ast.Stmt
+	if isDeclarationStart(p) {
+		init = parse_var_declaration_stmt(p)
+		if _, ok := init.(*ast.VarDeclarationStmt); !ok {
+			p.fail(diagnostic.Errorf(diagnostic.PARSE_UNEXPECTED_NODE, diagnostic.Span{Start: init.Pos(), End: init.End()}, "only variables can be declared in a for loop"))
+		}
+	} else if p.currentTokenKind() != lexer.SEMICOLON {
+		initStart := p.currentToken()
+		expr := parse_expr(p, default_bp)
+		p.expect(lexer.SEMICOLON)
+		init = &ast.ExprStmt{
+			Span: p.spanFrom(initStart),
+			Expr: expr,
+		}
 	} else {
-		kind = ast.SINGLE_LINE_COMMENT
+		p.advance()
 	}
 
-	return &ast.CommentStmt{
-		Value: p.advance().Value,
-		Type:  kind,
+	var cond ast.Expr
+	if p.currentTokenKind() != lexer.SEMICOLON {
+		cond = parse_expr(p, default_bp)
+	}
+	p.expect(lexer.SEMICOLON)
+
+	var post ast.Expr
+	if p.currentTokenKind() != lexer.RPAREN {
+		post = parse_expr(p, default_bp)
+	}
+	p.expect(lexer.RPAREN)
+
+	body := parseStmt(p)
+
+	return &ast.ForStmt{
+		Span: p.spanFrom(start),
+		Init: init,
+		Cond: cond,
+		Post: post,
+		Body: body,
+	}
+}
+
+func parse_switch_stmt(p *parser) ast.Stmt {
+	start := p.expect(lexer.SWITCH)
+
+	p.expect(lexer.LPAREN)
+	tag := parse_expr(p, default_bp)
+	p.expect(lexer.RPAREN)
+
+	p.expect(lexer.LBRACE)
+	p.switchDepth++
+	body := []ast.Stmt{}
+
+	for p.hasTokens() && p.currentTokenKind() != lexer.RBRACE {
+		body = append(body, parseStmt(p))
+	}
+
+	p.switchDepth--
+	p.expect(lexer.RBRACE)
+
+	// every clause but the last one runs into the next one unless it jumps
+	var previous *ast.CaseClause
+	for _, stmt := range body {
+		if clause, ok := stmt.(*ast.CaseClause); ok {
+			if previous != nil {
+				previous.Fallthrough = !isJump(previous.Body)
+			}
+			previous = clause
+		}
+	}
+
+	return &ast.SwitchStmt{
+		Span: p.spanFrom(start),
+		Tag:  tag,
+		Body: body,
+	}
+}
+
+// isJump reports whether the last statement of body is a jump, looking into
+// a trailing block or labeled statement. Other statements, like an if whose
+// branches all jump, are not analysed.
+func isJump(body []ast.Stmt) bool {
+	if len(body) == 0 {
+		return false
+	}
+
+	switch last := body[len(body)-1].(type) {
+	case *ast.BreakStmt, *ast.ContinueStmt, *ast.ReturnStmt, *ast.GotoStmt:
+		return true
+	case *ast.BlockStmt:
+		return isJump(last.Body)
+	case *ast.LabeledStmt:
+		return isJump([]ast.Stmt{last.Stmt})
+	}
+
+	return false
+}
+
+func isCaseLabel(kind lexer.TokenKind) bool {
+	return kind == lexer.CASE || kind == lexer.DEFAULT
+}
+
+// parse_case_clause parses consecutive case and default labels, and the
+// statements following them until the next label.
+func parse_case_clause(p *parser) ast.Stmt {
+	start := p.currentToken()
+	if p.switchDepth == 0 {
+		p.fail(diagnostic.Errorf(diagnostic.PARSE_UNEXPECTED_TOKEN, p.tokenSpan(start), "'%s' label not within a switch statement", start.Value))
+	}
+
+	labels := []ast.CaseLabel{}
+	for isCaseLabel(p.currentTokenKind()) {
+		labelStart := p.advance()
+		label := ast.CaseLabel{}
+
+		if labelStart.Kind == lexer.CASE {
+			label.Value = parse_expr(p, default_bp)
+
+			// GNU case range, `case 'a' ... 'z':`
+			if p.currentTokenKind() == lexer.ELLIPSIS {
+				p.advance()
+				label.High = parse_expr(p, default_bp)
+			}
+		}
+
+		p.expect(lexer.COLON)
+		label.Span = p.spanFrom(labelStart)
+		labels = append(labels, label)
+	}
+
+	body := []ast.Stmt{}
+	for p.hasTokens() && p.currentTokenKind() != lexer.RBRACE && !isCaseLabel(p.currentTokenKind()) {
+		body = append(body, parseStmt(p))
+	}
+
+	return &ast.CaseClause{
+		Span:   p.spanFrom(start),
+		Labels: labels,
+		Body:   body,
+	}
+}
+
+func parse_labeled_stmt(p *parser) ast.Stmt {
+	start := p.expect(lexer.IDENTIFIER)
+	p.expect(lexer.COLON)
+
+	stmt := parseStmt(p)
+
+	return &ast.LabeledStmt{
+		Span:  p.spanFrom(start),
+		Label: start.Value,
+		Stmt:  stmt,
+	}
+}
+
+func parse_goto_stmt(p *parser) ast.Stmt {
+	start := p.expect(lexer.GOTO)
+	label := p.expect(lexer.IDENTIFIER)
+	p.expect(lexer.SEMICOLON)
+
+	return &ast.GotoStmt{
+		Span:  p.spanFrom(start),
+		Label: label.Value,
+	}
+}
+
+// isDeclarationStart reports whether the current token starts a declaration.
+func isDeclarationStart(p *parser) bool {
+	switch kind := p.currentTokenKind(); {
+	case isType(kind), kind == lexer.CONST, kind == lexer.SIGNED, kind == lexer.UNSIGNED:
+		return true
+	}
+
+	return p.isTypeName(p.currentToken())
+}
+
+func parse_break_stmt(p *parser) ast.Stmt {
+	start := p.expect(lexer.BREAK)
+	p.expect(lexer.SEMICOLON)
+
+	return &ast.BreakStmt{
+		Span: p.spanFrom(start),
+	}
+}
+
+func parse_continue_stmt(p *parser) ast.Stmt {
+	start := p.expect(lexer.CONTINUE)
+	p.expect(lexer.SEMICOLON)
+
+	return &ast.ContinueStmt{
+		Span: p.spanFrom(start),
 	}
 }
 
 func parse_includer_stmt(p *parser) ast.Stmt {
-	p.expect(lexer.INCLUDER) // skip the includer token
+	includer := p.expect(lexer.INCLUDER)
+	pathToken := p.expect(lexer.INCLUDE_PATH)
+	path := pathToken.Value
+
+	stmt := &ast.IncluderStmt{
+		Value:  path,
+		Raw:    includer.Value,
+		System: strings.HasPrefix(path, "<"),
+	}
+
+	// the types of the standard headers are known even without the headers
+	if header, ok := libc.Lookup(strings.Trim(path, "<>\"")); ok && stmt.System {
+		for _, typedef := range header.Types {
+			p.typeNames[typedef.Name] = true
+		}
+	}
+
+	// declarations of the header, when the preprocessor loaded it
+	if p.currentTokenKind() == lexer.INCLUDE_START {
+		stmt.Path = p.advance().Value
+		stmt.Decls = []ast.Stmt{}
+
+		for p.hasTokens() && p.currentTokenKind() != lexer.INCLUDE_END {
+			stmt.Decls = append(stmt.Decls, parseStmt(p))
+		}
+
+		p.expect(lexer.INCLUDE_END)
+	}
+
+	// the span covers the #include line, not the header
+	stmt.Span = ast.Span{From: includer.Start, To: pathToken.End}
+
+	return stmt
+}
+
+func parse_typedef_stmt(p *parser) ast.Stmt {
+	start := p.expect(lexer.TYPEDEF)
+
+	isConst, isSigned, pointerLevel, varType, typeName, name := parse_var_type_and_name(p)
+	p.expect(lexer.SEMICOLON)
+
+	// typedef names are not scoped, they are known until the end of the file
+	p.typeNames[name] = true
+
+	return &ast.TypedefStmt{
+		Span:         p.spanFrom(start),
+		Name:         name,
+		IsConst:      isConst,
+		IsSigned:     isSigned,
+		PointerLevel: pointerLevel,
+		Type:         varType,
+		TypeName:     typeName,
+	}
+}
+
+func parse_directive_stmt(p *parser) ast.Stmt {
+	directive := p.expect(lexer.DIRECTIVE)
+
+	// the name is the first word after the '#' (or '%:')
+	name := strings.TrimLeft(strings.TrimPrefix(strings.TrimPrefix(directive.Value, "#"), "%:"), " \t")
+	if end := strings.IndexFunc(name, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' }); end >= 0 {
+		name = name[:end]
+	}
 
-	return &ast.IncluderStmt{
-		Value: p.advance().Value,
+	return &ast.DirectiveStmt{
+		Name: name,
+		Span: ast.Span{From: directive.Start, To: directive.End},
+		Raw:  directive.Value,
 	}
 }
@@ -0,0 +1,243 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ZiplEix/c_parser/src/ast"
+	"github.com/ZiplEix/c_parser/src/lexer"
+)
+
+// formatStmt writes the shape of a statement on a single line, its
+// expressions being written by format. Case clauses are between brackets,
+// followed by ↓ when they fall through.
+func formatStmt(stmt ast.Stmt) string {
+	switch s := stmt.(type) {
+	case nil:
+		return ""
+	case *ast.EmptyStmt:
+		return ";"
+	case *ast.ExprStmt:
+		return format(s.Expr) + ";"
+	case *ast.BlockStmt:
+		return "{ " + formatStmts(s.Body) + " }"
+	case *ast.VarDeclarationStmt:
+		if s.AssignedExpr == nil {
+			return "var " + s.Name + ";"
+		}
+		return fmt.Sprintf("var %s = %s;", s.Name, format(s.AssignedExpr))
+	case *ast.ReturnStmt:
+		if s.Expr == nil {
+			return "return;"
+		}
+		return fmt.Sprintf("return %s;", format(s.Expr))
+	case *ast.IfStmt:
+		if s.Else == nil {
+			return fmt.Sprintf("if %s %s", format(s.Cond), formatStmt(s.Then))
+		}
+		return fmt.Sprintf("if %s %s else %s", format(s.Cond), formatStmt(s.Then), formatStmt(s.Else))
+	case *ast.WhileStmt:
+		return fmt.Sprintf("while %s %s", format(s.Cond), formatStmt(s.Body))
+	case *ast.DoWhileStmt:
+		return fmt.Sprintf("do %s while %s;", formatStmt(s.Body), format(s.Cond))
+	case *ast.ForStmt:
+		init := ";"
+		if s.Init != nil {
+			init = formatStmt(s.Init)
+		}
+		return fmt.Sprintf("for (%s %s; %s) %s", init, formatOptional(s.Cond), formatOptional(s.Post), formatStmt(s.Body))
+	case *ast.BreakStmt:
+		return "break;"
+	case *ast.ContinueStmt:
+		return "continue;"
+	case *ast.SwitchStmt:
+		return fmt.Sprintf("switch %s { %s }", format(s.Tag), formatStmts(s.Body))
+	case *ast.CaseClause:
+		labels := make([]string, len(s.Labels))
+		for i, label := range s.Labels {
+			switch {
+			case label.Value == nil:
+				labels[i] = "default:"
+			case label.High == nil:
+				labels[i] = fmt.Sprintf("case %s:", format(label.Value))
+			default:
+				labels[i] = fmt.Sprintf("case %s ... %s:", format(label.Value), format(label.High))
+			}
+		}
+
+		clause := strings.Join(labels, " ")
+		if len(s.Body) > 0 {
+			clause += " " + formatStmts(s.Body)
+		}
+		if s.Fallthrough {
+			clause += " ↓"
+		}
+		return "[" + clause + "]"
+	case *ast.LabeledStmt:
+		return fmt.Sprintf("%s: %s", s.Label, formatStmt(s.Stmt))
+	case *ast.GotoStmt:
+		return fmt.Sprintf("goto %s;", s.Label)
+	default:
+		return fmt.Sprintf("%T", stmt)
+	}
+}
+
+func formatStmts(stmts []ast.Stmt) string {
+	formatted := make([]string, len(stmts))
+	for i, stmt := range stmts {
+		formatted[i] = formatStmt(stmt)
+	}
+	return strings.Join(formatted, " ")
+}
+
+// formatOptional is like format, a missing expression giving "".
+func formatOptional(expr ast.Expr) string {
+	if expr == nil {
+		return ""
+	}
+	return format(expr)
+}
+
+// parseBody parses the source as the body of a function, and returns the
+// shape of its statements.
+func parseBody(t *testing.T, source string) string {
+	t.Helper()
+
+	tokens, diagnostics := lexer.Tokensize("void f(void) {\n" + source + "\n}")
+	if len(diagnostics) > 0 {
+		t.Fatalf("unexpected lexer diagnostics: %v", diagnostics)
+	}
+
+	block, diagnostics := Parse(tokens)
+	if len(diagnostics) > 0 {
+		t.Fatalf("unexpected diagnostics: %v", diagnostics)
+	}
+	if len(block.Body) != 1 {
+		t.Fatalf("got %d statements, want 1", len(block.Body))
+	}
+
+	function, ok := block.Body[0].(*ast.FunctionDeclarationStmt)
+	if !ok {
+		t.Fatalf("got %T, want *ast.FunctionDeclarationStmt", block.Body[0])
+	}
+	return formatStmts(function.Body)
+}
+
+func TestParseControlStmt(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{";", ";"},
+		{"return;", "return;"},
+		{"return 1 + 2;", "return (1 + 2);"},
+		{"{ ; }", "{ ; }"},
+
+		{"if (a) b;", "if a b;"},
+		{"if (a) b; else c;", "if a b; else c;"},
+		{"if (a) if (b) c; else d;", "if a if b c; else d;"},
+		{"if (a) { b; } else if (c) ; else { }", "if a { b; } else if c ; else {  }"},
+
+		{"while (*p++) ;", "while (*(p++)) ;"},
+		{"while (i < n) { i++; continue; }", "while (i < n) { (i++); continue; }"},
+		{"do i--; while (i);", "do (i--); while i;"},
+		{"do { break; } while (1);", "do { break; } while 1;"},
+
+		{"for (;;) ;", "for (; ; ) ;"},
+		{"for (;;) break;", "for (; ; ) break;"},
+		{"for (int i = 0; i < n; i++) f(i);", "for (var i = 0; (i < n); (i++)) f(i);"},
+		{"for (i = 0; i < n; i += 2) ;", "for ((i = 0); (i < n); (i += 2)) ;"},
+		{"for (int i; ; ) { }", "for (var i; ; ) {  }"},
+		{"for (; i; ) continue;", "for (; i; ) continue;"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			if got := parseBody(t, test.source); got != test.want {
+				t.Errorf("got\n\t%s\nwant\n\t%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseSwitchStmt(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"switch (x) { }", "switch x {  }"},
+		{
+			"switch (x) { case 1: a; break; case 2: case 3: b; default: c; }",
+			"switch x { [case 1: a; break;] [case 2: case 3: b; ↓] [default: c;] }",
+		},
+		{
+			"switch (x) { case 1 ... 5: a; break; case 'a' ... 'z': b; }",
+			"switch x { [case 1 ... 5: a; break;] [case 'a' ... 'z': b;] }",
+		},
+		{"switch (x) { case -1: case 1 + 1: ; }", "switch x { [case (-1): case (1 + 1): ;] }"},
+		{"switch (x) { a; case 1: b; }", "switch x { a; [case 1: b;] }"},
+		{"switch (x) { case 1: ; case 2: ; }", "switch x { [case 1: ; ↓] [case 2: ;] }"},
+		{"switch (x) { case 1: default: ; }", "switch x { [case 1: default: ;] }"},
+
+		// the jump ending a clause may be at the end of a block or after a label
+		{
+			"switch (x) { case 1: { a; break; } case 2: { return; } case 3: ; }",
+			"switch x { [case 1: { a; break; }] [case 2: { return; }] [case 3: ;] }",
+		},
+		{
+			"switch (x) { case 1: { { continue; } } case 2: out: goto out; case 3: ; }",
+			"switch x { [case 1: { { continue; } }] [case 2: out: goto out;] [case 3: ;] }",
+		},
+		{
+			"switch (x) { case 1: { break; a; } case 2: if (a) break; else return; case 3: ; }",
+			"switch x { [case 1: { break; a; } ↓] [case 2: if a break; else return; ↓] [case 3: ;] }",
+		},
+		{
+			"switch (x) { case 1: switch (y) { case 2: break; } case 3: ; }",
+			"switch x { [case 1: switch y { [case 2: break;] } ↓] [case 3: ;] }",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			if got := parseBody(t, test.source); got != test.want {
+				t.Errorf("got\n\t%s\nwant\n\t%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseLabeledStmt(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"goto end; end: ;", "goto end; end: ;"},
+		{"end: return;", "end: return;"},
+		{"a: b: c;", "a: b: c;"},
+		{"again: if (x) goto again;", "again: if x goto again;"},
+		{"a; b: { c; }", "a; b: { c; }"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			if got := parseBody(t, test.source); got != test.want {
+				t.Errorf("got\n\t%s\nwant\n\t%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseCaseOutsideSwitch(t *testing.T) {
+	for _, source := range []string{"case 1: ;", "default: ;", "switch (x) { } case 1: ;"} {
+		t.Run(source, func(t *testing.T) {
+			tokens, _ := lexer.Tokensize("void f(void) {\n" + source + "\n}")
+			_, diagnostics := Parse(tokens)
+
+			if len(diagnostics) != 1 || !strings.Contains(diagnostics[0].Message, "not within a switch statement") {
+				t.Errorf("got %v, want a label not within a switch statement", diagnostics)
+			}
+		})
+	}
+}
@@ -0,0 +1,335 @@
+package preprocessor
+
+import (
+	"strconv"
+
+	"github.com/ZiplEix/c_parser/src/diagnostic"
+	"github.com/ZiplEix/c_parser/src/lexer"
+)
+
+// value is an integer of a #if expression, computed as intmax_t or uintmax_t.
+type value struct {
+	bits       uint64
+	isUnsigned bool
+}
+
+func (v value) isTrue() bool {
+	return v.bits != 0
+}
+
+func boolValue(b bool) value {
+	if b {
+		return value{bits: 1}
+	}
+	return value{}
+}
+
+// evaluator computes the value of the controlling expression of #if and #elif.
+type evaluator struct {
+	pp        *preprocessor
+	directive lexer.Token
+	tokens    []lexer.Token
+	pos       int
+	failed    bool
+}
+
+// evaluate replaces the defined operators, expands the macros of the
+// expression and computes it. Errors are reported and evaluate to false.
+func (pp *preprocessor) evaluate(directive lexer.Token, line []item) bool {
+	if len(line) == 0 {
+		pp.errorf(diagnostic.PP_INVALID_EXPRESSION, directive, "#%s with no expression", spelling(directive))
+		return false
+	}
+
+	expanded := pp.expandList(pp.replaceDefined(line))
+	tokens := make([]lexer.Token, 0, len(expanded))
+
+	for _, it := range expanded {
+		token := it.token
+
+		// identifiers left after macro expansion are replaced by 0
+		if isIdentifier(token) {
+			value := "0"
+			if token.Value == "true" {
+				value = "1"
+			}
+			token.Kind = lexer.INTEGER
+			token.Value = value
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	ev := &evaluator{pp: pp, directive: directive, tokens: tokens}
+	result := ev.expression(0, true)
+
+	if !ev.failed && ev.pos < len(ev.tokens) {
+		ev.errorf(ev.tokens[ev.pos], "missing binary operator before token \"%s\"", spelling(ev.tokens[ev.pos]))
+	}
+
+	return !ev.failed && result.isTrue()
+}
+
+// replaceDefined evaluates the `defined X` and `defined(X)` operators, before
+// macro expansion.
+func (pp *preprocessor) replaceDefined(line []item) []item {
+	result := make([]item, 0, len(line))
+
+	for i := 0; i < len(line); i++ {
+		token := line[i].token
+		if token.Kind != lexer.IDENTIFIER || token.Value != "defined" {
+			result = append(result, line[i])
+			continue
+		}
+
+		j := i + 1
+		parenthesized := j < len(line) && line[j].token.Kind == lexer.LPAREN
+		if parenthesized {
+			j++
+		}
+
+		if j >= len(line) || !isIdentifier(line[j].token) {
+			pp.errorf(diagnostic.PP_INVALID_EXPRESSION, token, "operator \"defined\" requires an identifier")
+			result = append(result, line[i])
+			continue
+		}
+
+		_, defined := pp.macros[line[j].token.Value]
+
+		if parenthesized {
+			j++
+			if j >= len(line) || line[j].token.Kind != lexer.RPAREN {
+				pp.errorf(diagnostic.PP_INVALID_EXPRESSION, token, "missing ')' after \"defined\"")
+				j--
+			}
+		}
+
+		token.Kind = lexer.INTEGER
+		token.Value = "0"
+		if defined {
+			token.Value = "1"
+		}
+
+		result = append(result, item{token: token})
+		i = j
+	}
+
+	return result
+}
+
+func (ev *evaluator) errorf(at lexer.Token, format string, args ...any) {
+	if !ev.failed {
+		ev.pp.errorf(diagnostic.PP_INVALID_EXPRESSION, at, format, args...)
+	}
+	ev.failed = true
+}
+
+func (ev *evaluator) current() (lexer.Token, bool) {
+	if ev.pos < len(ev.tokens) {
+		return ev.tokens[ev.pos], true
+	}
+	return ev.directive, false
+}
+
+// binaryPrecedence gives the precedence of the binary operators, higher binds
+// tighter. The conditional operator is handled separately at level 1.
+var binaryPrecedence = map[lexer.TokenKind]int{
+	lexer.LOGICAL_OR:    2,
+	lexer.LOGICAL_AND:   3,
+	lexer.PIPE:          4,
+	lexer.CARET:         5,
+	lexer.ESPERLUETTE:   6,
+	lexer.EQUAL:         7,
+	lexer.NOT_EQUAL:     7,
+	lexer.LESS:          8,
+	lexer.LESS_EQUAL:    8,
+	lexer.GREATER:       8,
+	lexer.GREATER_EQUAL: 8,
+	lexer.SHIFT_LEFT:    9,
+	lexer.SHIFT_RIGHT:   9,
+	lexer.PLUS:          10,
+	lexer.MINUS:         10,
+	lexer.STAR:          11,
+	lexer.SLASH:         11,
+	lexer.PERCENT:       11,
+}
+
+// expression parses operators binding tighter than minPrecedence. When
+// evaluated is false the operands are only parsed (right side of a
+// short-circuited operator), so division by zero is not reported.
+func (ev *evaluator) expression(minPrecedence int, evaluated bool) value {
+	left := ev.unary(evaluated)
+
+	for {
+		token, ok := ev.current()
+		if !ok || ev.failed {
+			return left
+		}
+
+		if token.Kind == lexer.QUESTION && minPrecedence <= 1 {
+			ev.pos++
+			thenValue := ev.expression(0, evaluated && left.isTrue())
+			if next, ok := ev.current(); !ok || next.Kind != lexer.COLON {
+				ev.errorf(next, "expected ':' in conditional expression")
+				return left
+			}
+			ev.pos++
+			elseValue := ev.expression(1, evaluated && !left.isTrue())
+
+			result := elseValue
+			if left.isTrue() {
+				result = thenValue
+			}
+			result.isUnsigned = thenValue.isUnsigned || elseValue.isUnsigned
+			left = result
+			continue
+		}
+
+		precedence, isBinary := binaryPrecedence[token.Kind]
+		if !isBinary || precedence <= minPrecedence {
+			return left
+		}
+		ev.pos++
+
+		rightEvaluated := evaluated
+		if token.Kind == lexer.LOGICAL_AND {
+			rightEvaluated = evaluated && left.isTrue()
+		} else if token.Kind == lexer.LOGICAL_OR {
+			rightEvaluated = evaluated && !left.isTrue()
+		}
+
+		right := ev.expression(precedence, rightEvaluated)
+		left = ev.binary(token, left, right, rightEvaluated)
+	}
+}
+
+func (ev *evaluator) binary(operator lexer.Token, left, right value, evaluated bool) value {
+	isUnsigned := left.isUnsigned || right.isUnsigned
+	result := value{isUnsigned: isUnsigned}
+	l, r := left.bits, right.bits
+
+	less := func() bool {
+		if isUnsigned {
+			return l < r
+		}
+		return int64(l) < int64(r)
+	}
+
+	switch operator.Kind {
+	case lexer.LOGICAL_OR:
+		return boolValue(left.isTrue() || right.isTrue())
+	case lexer.LOGICAL_AND:
+		return boolValue(left.isTrue() && right.isTrue())
+	case lexer.PIPE:
+		result.bits = l | r
+	case lexer.CARET:
+		result.bits = l ^ r
+	case lexer.ESPERLUETTE:
+		result.bits = l & r
+	case lexer.EQUAL:
+		return boolValue(l == r)
+	case lexer.NOT_EQUAL:
+		return boolValue(l != r)
+	case lexer.LESS:
+		return boolValue(less())
+	case lexer.GREATER_EQUAL:
+		return boolValue(!less())
+	case lexer.GREATER:
+		return boolValue(l != r && !less())
+	case lexer.LESS_EQUAL:
+		return boolValue(l == r || less())
+	case lexer.SHIFT_LEFT:
+		result = value{bits: l << (r & 63), isUnsigned: left.isUnsigned}
+	case lexer.SHIFT_RIGHT:
+		if left.isUnsigned {
+			result = value{bits: l >> (r & 63), isUnsigned: true}
+		} else {
+			result = value{bits: uint64(int64(l) >> (r & 63))}
+		}
+	case lexer.PLUS:
+		result.bits = l + r
+	case lexer.MINUS:
+		result.bits = l - r
+	case lexer.STAR:
+		result.bits = l * r
+	case lexer.SLASH, lexer.PERCENT:
+		if r == 0 {
+			if evaluated {
+				ev.errorf(operator, "division by zero in #%s", spelling(ev.directive))
+			}
+			return value{}
+		}
+
+		switch {
+		case isUnsigned && operator.Kind == lexer.SLASH:
+			result.bits = l / r
+		case isUnsigned:
+			result.bits = l % r
+		case operator.Kind == lexer.SLASH:
+			result.bits = uint64(int64(l) / int64(r))
+		default:
+			result.bits = uint64(int64(l) % int64(r))
+		}
+	}
+
+	return result
+}
+
+func (ev *evaluator) unary(evaluated bool) value {
+	token, ok := ev.current()
+	if !ok {
+		ev.errorf(token, "#%s expression is incomplete", spelling(ev.directive))
+		return value{}
+	}
+
+	switch token.Kind {
+	case lexer.PLUS:
+		ev.pos++
+		return ev.unary(evaluated)
+	case lexer.MINUS:
+		ev.pos++
+		operand := ev.unary(evaluated)
+		return value{bits: -operand.bits, isUnsigned: operand.isUnsigned}
+	case lexer.TILDE:
+		ev.pos++
+		operand := ev.unary(evaluated)
+		return value{bits: ^operand.bits, isUnsigned: operand.isUnsigned}
+	case lexer.LOGICAL_NOT:
+		ev.pos++
+		return boolValue(!ev.unary(evaluated).isTrue())
+	case lexer.LPAREN:
+		ev.pos++
+		result := ev.expression(0, evaluated)
+		if next, ok := ev.current(); !ok || next.Kind != lexer.RPAREN {
+			ev.errorf(next, "missing ')' in expression")
+			return result
+		}
+		ev.pos++
+		return result
+	case lexer.INTEGER, lexer.UNSIGNED_INTEGER:
+		ev.pos++
+		literal, err := lexer.DecodeInteger(token.Value)
+		if err != nil {
+			ev.errorf(token, "%s", err)
+			return value{}
+		}
+
+		bits, err := strconv.ParseUint(literal.Digits, literal.Base, 64)
+		if err != nil {
+			ev.errorf(token, "integer constant '%s' is too large", token.Value)
+			return value{}
+		}
+		return value{bits: bits, isUnsigned: literal.IsUnsigned || bits > 1<<63-1}
+	case lexer.CHARACTER:
+		ev.pos++
+		literal, err := lexer.DecodeChar(token.Value)
+		if err != nil {
+			ev.errorf(token, "%s", err)
+			return value{}
+		}
+		return value{bits: uint64(literal.Value)}
+	default:
+		ev.errorf(token, "token \"%s\" is not valid in preprocessor expressions", spelling(token))
+		return value{}
+	}
+}
@@ -0,0 +1,277 @@
+package preprocessor
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/ZiplEix/c_parser/src/diagnostic"
+	"github.com/ZiplEix/c_parser/src/lexer"
+)
+
+// maxIncludeDepth bounds the nesting of #include, to stop on include cycles
+// that are not protected by a guard.
+const maxIncludeDepth = 200
+
+// includes is the state shared by a file and the headers it includes.
+type includes struct {
+	once   map[string]bool   // files containing #pragma once
+	guards map[string]string // files wrapped in an #ifndef guard, with its macro
+	loaded map[string]bool   // files already included
+	depth  int
+}
+
+func newIncludes() *includes {
+	return &includes{
+		once:   map[string]bool{},
+		guards: map[string]string{},
+		loaded: map[string]bool{},
+	}
+}
+
+// include handles an `#include <...>` or `#include "..."` line scanned by the
+// lexer as INCLUDER and INCLUDE_PATH tokens.
+func (pp *preprocessor) include(includer lexer.Token, line []item) {
+	last := includer
+	if len(line) > 0 {
+		last = line[len(line)-1].token
+	}
+	includer.Value = pp.rawText(includer, last, line)
+
+	if len(line) == 0 || line[0].token.Kind != lexer.INCLUDE_PATH {
+		pp.errorf(diagnostic.PP_INVALID_DIRECTIVE, includer, "#include expects \"FILENAME\" or <FILENAME>")
+		return
+	}
+
+	pp.extraTokens(includer, line[1:])
+	pp.emitInclude(includer, line[0].token)
+}
+
+// computedInclude handles the forms of #include the lexer does not recognize,
+// like `# include <stdio.h>` or `#include HEADER`. line starts with the
+// `include` name, the rest of it is macro-expanded when expand is set.
+func (pp *preprocessor) computedInclude(hash lexer.Token, line []item, expand bool) {
+	name := line[0].token
+	args := line[1:]
+	if expand {
+		args = pp.expandList(args)
+	}
+
+	path, rest, ok := headerName(args)
+	if !ok {
+		pp.errorf(diagnostic.PP_INVALID_DIRECTIVE, name, "#include expects \"FILENAME\" or <FILENAME>")
+		return
+	}
+	pp.extraTokens(name, rest)
+
+	includer := hash
+	includer.Kind = lexer.INCLUDER
+	includer.Value = pp.rawText(hash, line[len(line)-1].token, line)
+	includer.End = name.End
+
+	pp.emitInclude(includer, path)
+}
+
+// headerName reads a "..." or <...> header name at the start of tokens and
+// returns it as an INCLUDE_PATH token, followed by the remaining tokens.
+func headerName(tokens []item) (lexer.Token, []item, bool) {
+	if len(tokens) == 0 {
+		return lexer.Token{}, nil, false
+	}
+
+	path := tokens[0].token
+
+	if path.Kind == lexer.STRING && len(path.Value) >= 2 && path.Value[0] == '"' {
+		path.Kind = lexer.INCLUDE_PATH
+		return path, tokens[1:], true
+	}
+
+	if path.Kind != lexer.LESS {
+		return lexer.Token{}, nil, false
+	}
+
+	for i := 1; i < len(tokens); i++ {
+		if tokens[i].token.Kind == lexer.GREATER {
+			path.Kind = lexer.INCLUDE_PATH
+			path.Value = "<" + joinSpelling(tokens[1:i]) + ">"
+			path.End = tokens[i].token.End
+			return path, tokens[i+1:], true
+		}
+	}
+
+	return lexer.Token{}, nil, false
+}
+
+// emitInclude keeps the #include line for the parser, followed by the tokens
+// of the header between INCLUDE_START and INCLUDE_END when it is loaded.
+func (pp *preprocessor) emitInclude(includer, path lexer.Token) {
+	pp.output = append(pp.output, includer, path)
+
+	name := path.Value
+	if len(name) < 2 {
+		return
+	}
+	system := name[0] == '<'
+	name = name[1 : len(name)-1]
+
+	file, found := pp.resolve(name, system)
+	if !found {
+		// system headers unknown to the search paths are left to the C compiler
+		if !system {
+			pp.errorf(diagnostic.PP_INCLUDE_NOT_FOUND, path, "'%s' file not found", name)
+		}
+		return
+	}
+
+	header, ok := pp.load(includer, file)
+	if !ok {
+		return
+	}
+
+	start := includer
+	start.Kind = lexer.INCLUDE_START
+	start.Value = file
+	start.Leading, start.Trailing = nil, nil
+
+	end := start
+	end.Kind = lexer.INCLUDE_END
+
+	pp.output = append(pp.output, start)
+	pp.output = append(pp.output, header...)
+	pp.output = append(pp.output, end)
+}
+
+// resolve looks for a header: quoted names are searched in the directory of
+// the current file, then in the include paths and the system paths, names
+// between angle brackets only in the include paths and the system paths.
+func (pp *preprocessor) resolve(name string, system bool) (string, bool) {
+	if filepath.IsAbs(name) {
+		return filepath.Clean(name), isFile(name)
+	}
+
+	dirs := []string{}
+	if !system {
+		dirs = append(dirs, filepath.Dir(pp.config.File))
+	}
+	dirs = append(dirs, pp.config.IncludePaths...)
+	dirs = append(dirs, pp.config.SystemPaths...)
+
+	for _, dir := range dirs {
+		path := filepath.Join(dir, name)
+		if isFile(path) {
+			return path, true
+		}
+	}
+
+	return "", false
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// load preprocesses a header with the macros of the current file and returns
+// its tokens, without the EOF. It returns false if the header must not be
+// included again or cannot be read.
+func (pp *preprocessor) load(includer lexer.Token, file string) ([]lexer.Token, bool) {
+	if pp.includes.once[file] {
+		return nil, false
+	}
+	if guard, ok := pp.includes.guards[file]; ok {
+		if _, defined := pp.macros[guard]; defined {
+			return nil, false
+		}
+	}
+	if pp.config.Mode == PASS_THROUGH && pp.includes.loaded[file] {
+		// macros are not tracked in this mode, guards cannot be evaluated
+		return nil, false
+	}
+	if pp.includes.depth >= maxIncludeDepth {
+		pp.errorf(diagnostic.PP_INCLUDE_NOT_FOUND, includer, "#include nested too deeply")
+		return nil, false
+	}
+
+	source, err := os.ReadFile(file)
+	if err != nil {
+		pp.errorf(diagnostic.PP_INCLUDE_NOT_FOUND, includer, "cannot read '%s': %s", file, err)
+		return nil, false
+	}
+
+	tokens, diagnostics := lexer.TokensizeOptions(file, string(source), lexer.Options{PreC23: pp.config.PreC23})
+	pp.diagnostics = append(pp.diagnostics, diagnostics...)
+
+	if guard := includeGuard(tokens); guard != "" {
+		pp.includes.guards[file] = guard
+	}
+	pp.includes.loaded[file] = true
+
+	config := pp.config
+	config.File = file
+	config.Source = string(source)
+
+	sub := &preprocessor{
+		config:   config,
+		macros:   pp.macros,
+		includes: pp.includes,
+		date:     pp.date,
+		time:     pp.time,
+		counter:  pp.counter,
+	}
+
+	pp.includes.depth++
+	if config.Mode == PASS_THROUGH {
+		sub.passThrough(newStream(tokens, true))
+	} else {
+		sub.run(newStream(tokens, true))
+	}
+	pp.includes.depth--
+
+	pp.counter = sub.counter
+	pp.diagnostics = append(pp.diagnostics, sub.diagnostics...)
+
+	return sub.output[:len(sub.output)-1], true
+}
+
+// pragmaOnce marks the current file as not to be included again.
+func (pp *preprocessor) pragmaOnce() {
+	pp.includes.once[filepath.Clean(pp.config.File)] = true
+}
+
+// includeGuard returns the macro of the `#ifndef NAME` ... `#endif` guard
+// wrapping the whole file, or "" if the file has none.
+func includeGuard(tokens []lexer.Token) string {
+	if len(tokens) < 4 || !isDirectiveStart(tokens[0]) || tokens[0].Kind != lexer.POUND ||
+		tokens[1].Value != "ifndef" || !isIdentifier(tokens[2]) || !tokens[3].AtLineStart {
+		return ""
+	}
+
+	depth := 0
+	for i := 0; i < len(tokens)-1; i++ {
+		if !isDirectiveStart(tokens[i]) || tokens[i].Kind != lexer.POUND || tokens[i+1].AtLineStart {
+			continue
+		}
+
+		switch tokens[i+1].Value {
+		case "if", "ifdef", "ifndef":
+			depth++
+		case "endif":
+			depth--
+			if depth > 0 {
+				continue
+			}
+
+			// the #endif closing the guard must be the last line of the file
+			for j := i + 2; j < len(tokens); j++ {
+				if tokens[j].AtLineStart || tokens[j].Kind == lexer.EOF {
+					if tokens[j].Kind == lexer.EOF {
+						return tokens[2].Value
+					}
+					return ""
+				}
+			}
+			return ""
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,622 @@
+package preprocessor
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ZiplEix/c_parser/src/diagnostic"
+	"github.com/ZiplEix/c_parser/src/lexer"
+)
+
+// hideset is the set of macros that must not be expanded again in a token,
+// it is never modified once created.
+type hideset map[string]bool
+
+func (h hideset) with(name string) hideset {
+	result := make(hideset, len(h)+1)
+	for key := range h {
+		result[key] = true
+	}
+	result[name] = true
+	return result
+}
+
+func (h hideset) union(other hideset) hideset {
+	if len(other) == 0 {
+		return h
+	}
+	if len(h) == 0 {
+		return other
+	}
+
+	result := make(hideset, len(h)+len(other))
+	for key := range h {
+		result[key] = true
+	}
+	for key := range other {
+		result[key] = true
+	}
+	return result
+}
+
+func (h hideset) intersect(other hideset) hideset {
+	result := hideset{}
+	for key := range h {
+		if other[key] {
+			result[key] = true
+		}
+	}
+	return result
+}
+
+// item is a token going through macro expansion.
+type item struct {
+	token lexer.Token
+	hide  hideset
+}
+
+// placemarker stands for an empty macro argument next to a ## operator.
+const placemarker lexer.TokenKind = -1
+
+// stream is the input of the macro expander: the tokens of a file (main) or
+// of an isolated list such as a macro argument, with the expansions waiting
+// to be rescanned in front of them.
+type stream struct {
+	pending []item // expanded tokens to read first, the next one is at the end
+	items   []item
+	pos     int
+	main    bool              // directives can be found in the stream
+	source  lexer.TokenSource // where the items are read from when they are not all in memory
+}
+
+func newStream(tokens []lexer.Token, main bool) *stream {
+	items := make([]item, len(tokens))
+	for i, token := range tokens {
+		items[i] = item{token: token}
+	}
+	return &stream{items: items, main: main}
+}
+
+// newSourceStream returns a main stream reading its tokens from source as
+// they are needed.
+func newSourceStream(source lexer.TokenSource) *stream {
+	return &stream{main: true, source: source}
+}
+
+func (s *stream) peek() (item, bool) {
+	if len(s.pending) > 0 {
+		return s.pending[len(s.pending)-1], true
+	}
+	if s.pos >= len(s.items) && s.source != nil {
+		// the items read are forgotten once consumed
+		token := s.source.Next()
+		s.items, s.pos = append(s.items[:0], item{token: token}), 0
+		if token.Kind == lexer.EOF {
+			s.source = nil
+		}
+	}
+	if s.pos < len(s.items) {
+		return s.items[s.pos], true
+	}
+	return item{token: lexer.Token{Kind: lexer.EOF}}, false
+}
+
+func (s *stream) next() (item, bool) {
+	it, ok := s.peek()
+	if !ok {
+		return it, false
+	}
+
+	if len(s.pending) > 0 {
+		s.pending = s.pending[:len(s.pending)-1]
+	} else {
+		s.pos++
+	}
+	return it, true
+}
+
+// push puts items in front of the stream, they will be read in order.
+func (s *stream) push(items []item) {
+	for i := len(items) - 1; i >= 0; i-- {
+		s.pending = append(s.pending, items[i])
+	}
+}
+
+type macro struct {
+	name       string
+	isFunction bool
+	params     []string
+	isVariadic bool
+	body       []lexer.Token
+	builtin    func(pp *preprocessor, at lexer.Token) lexer.Token
+	definition lexer.Token
+}
+
+func (pp *preprocessor) defineBuiltins() {
+	version := pp.config.Version
+	if version == "" {
+		version = "201710L"
+	}
+
+	for name, value := range map[string]string{
+		"__STDC__":         "1",
+		"__STDC_HOSTED__":  "1",
+		"__STDC_VERSION__": version,
+	} {
+		tokens, _ := lexer.Tokensize(value)
+		pp.macros[name] = &macro{name: name, body: tokens[:len(tokens)-1]}
+	}
+
+	builtin := func(name string, kind lexer.TokenKind, value func(pp *preprocessor, at lexer.Token) string) {
+		pp.macros[name] = &macro{
+			name: name,
+			builtin: func(pp *preprocessor, at lexer.Token) lexer.Token {
+				token := at
+				token.Kind = kind
+				token.Value = value(pp, at)
+				return token
+			},
+		}
+	}
+
+	builtin("__FILE__", lexer.STRING, func(pp *preprocessor, at lexer.Token) string {
+		return fmt.Sprintf("%q", pp.config.File)
+	})
+	builtin("__LINE__", lexer.INTEGER, func(pp *preprocessor, at lexer.Token) string {
+		return fmt.Sprint(at.Start.Line)
+	})
+	builtin("__DATE__", lexer.STRING, func(pp *preprocessor, at lexer.Token) string {
+		return pp.date
+	})
+	builtin("__TIME__", lexer.STRING, func(pp *preprocessor, at lexer.Token) string {
+		return pp.time
+	})
+	builtin("__COUNTER__", lexer.INTEGER, func(pp *preprocessor, at lexer.Token) string {
+		pp.counter++
+		return fmt.Sprint(pp.counter - 1)
+	})
+}
+
+// define handles `#define NAME body` and `#define NAME(params) body`.
+func (pp *preprocessor) define(directive lexer.Token, args []item) {
+	if len(args) == 0 || !isIdentifier(args[0].token) {
+		pp.errorf(diagnostic.PP_INVALID_MACRO, directive, "macro name must be an identifier")
+		return
+	}
+
+	nameToken := args[0].token
+	m := &macro{name: spelling(nameToken), definition: nameToken}
+	rest := args[1:]
+
+	if m.name == "defined" {
+		pp.errorf(diagnostic.PP_INVALID_MACRO, nameToken, "\"defined\" cannot be used as a macro name")
+		return
+	}
+
+	// a function-like macro has its parenthesis right after the name
+	if len(rest) > 0 && rest[0].token.Kind == lexer.LPAREN && !rest[0].token.HasSpace {
+		m.isFunction = true
+
+		var ok bool
+		if m.params, m.isVariadic, rest, ok = pp.parseParams(nameToken, rest[1:]); !ok {
+			return
+		}
+	}
+
+	for _, it := range rest {
+		m.body = append(m.body, it.token)
+	}
+
+	if !pp.validBody(m) {
+		return
+	}
+
+	if previous, exists := pp.macros[m.name]; exists && !sameDefinition(previous, m) {
+		d := diagnostic.Warningf(diagnostic.PP_MACRO_REDEFINED, diagnostic.Span{Start: nameToken.Start, End: nameToken.End}, "\"%s\" redefined", m.name)
+		if previous.definition.Start.IsValid() {
+			d = d.WithNote(diagnostic.Span{Start: previous.definition.Start, End: previous.definition.End}, "previous definition is here")
+		}
+		pp.diagnostics = append(pp.diagnostics, d)
+	}
+
+	pp.macros[m.name] = m
+}
+
+// parseParams reads the parameter list of a function-like macro, after its
+// opening parenthesis, and returns the tokens following it.
+func (pp *preprocessor) parseParams(name lexer.Token, args []item) ([]string, bool, []item, bool) {
+	params := []string{}
+	isVariadic := false
+
+	for i := 0; i < len(args); i++ {
+		token := args[i].token
+
+		switch {
+		case token.Kind == lexer.RPAREN && len(params) == 0 && !isVariadic:
+			return params, isVariadic, args[i+1:], true
+		case token.Kind == lexer.ELLIPSIS:
+			isVariadic = true
+		case isIdentifier(token) && !isVariadic:
+			if slices.Contains(params, token.Value) {
+				pp.errorf(diagnostic.PP_INVALID_MACRO, token, "duplicate macro parameter \"%s\"", token.Value)
+				return nil, false, nil, false
+			}
+			params = append(params, token.Value)
+		default:
+			pp.errorf(diagnostic.PP_INVALID_MACRO, token, "invalid token in macro parameter list")
+			return nil, false, nil, false
+		}
+
+		if i+1 < len(args) && args[i+1].token.Kind == lexer.RPAREN {
+			return params, isVariadic, args[i+2:], true
+		}
+		if i+1 >= len(args) || args[i+1].token.Kind != lexer.COMMA {
+			break
+		}
+		i++
+	}
+
+	pp.errorf(diagnostic.PP_INVALID_MACRO, name, "missing ')' in macro parameter list")
+	return nil, false, nil, false
+}
+
+func (pp *preprocessor) validBody(m *macro) bool {
+	body := m.body
+	if len(body) == 0 {
+		return true
+	}
+
+	if body[0].Kind == lexer.POUND_POUND || body[len(body)-1].Kind == lexer.POUND_POUND {
+		pp.errorf(diagnostic.PP_INVALID_MACRO, m.definition, "'##' cannot appear at either end of a macro expansion")
+		return false
+	}
+
+	if m.isVariadic && pp.config.PreC23 {
+		for _, token := range body {
+			if token.Kind == lexer.IDENTIFIER && token.Value == "__VA_OPT__" {
+				pp.errorf(diagnostic.PP_C23_FEATURE, token, "__VA_OPT__ is a C23 feature")
+				break
+			}
+		}
+	}
+
+	if m.isFunction {
+		for i, token := range body {
+			if token.Kind == lexer.POUND && (i+1 >= len(body) || m.paramIndex(body[i+1]) < 0) {
+				pp.errorf(diagnostic.PP_INVALID_MACRO, token, "'#' is not followed by a macro parameter")
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func sameDefinition(a, b *macro) bool {
+	if a.builtin != nil || b.builtin != nil || a.isFunction != b.isFunction || a.isVariadic != b.isVariadic ||
+		!slices.Equal(a.params, b.params) || len(a.body) != len(b.body) {
+		return false
+	}
+
+	for i := range a.body {
+		if a.body[i].Kind != b.body[i].Kind || a.body[i].Value != b.body[i].Value || (i > 0 && a.body[i].HasSpace != b.body[i].HasSpace) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// paramIndex returns the index of the parameter named by token, __VA_ARGS__
+// being the one after the named parameters, or -1.
+func (m *macro) paramIndex(token lexer.Token) int {
+	if !m.isFunction || !isIdentifier(token) {
+		return -1
+	}
+
+	if m.isVariadic && token.Value == "__VA_ARGS__" {
+		return len(m.params)
+	}
+
+	return slices.Index(m.params, token.Value)
+}
+
+// expand replaces a macro invocation starting with it by its expansion, which
+// is pushed back on the stream to be rescanned. It returns false when it is
+// not an invocation.
+func (pp *preprocessor) expand(s *stream, it item) bool {
+	if !isIdentifier(it.token) || it.hide[spelling(it.token)] {
+		return false
+	}
+
+	m, exists := pp.macros[spelling(it.token)]
+	if !exists {
+		return false
+	}
+
+	if m.builtin != nil {
+		s.push([]item{{token: m.builtin(pp, it.token), hide: it.hide}})
+		return true
+	}
+
+	if !m.isFunction {
+		s.push(pp.substitute(m, m.body, nil, it.hide.with(m.name), it.token))
+		return true
+	}
+
+	next, ok := s.peek()
+	if !ok || next.token.Kind != lexer.LPAREN || (s.main && isDirectiveStart(next.token)) {
+		return false
+	}
+	s.next()
+
+	args, rparen, ok := pp.collectArgs(s, m, it.token)
+	if !ok {
+		return true
+	}
+
+	hide := it.hide.intersect(rparen.hide).with(m.name)
+	s.push(pp.substitute(m, m.body, args, hide, it.token))
+	return true
+}
+
+// collectArgs reads the arguments of a function-like macro invocation, after
+// its opening parenthesis, and returns them with the closing parenthesis.
+func (pp *preprocessor) collectArgs(s *stream, m *macro, invocation lexer.Token) ([][]item, item, bool) {
+	args := [][]item{{}}
+	depth := 0
+
+	for {
+		it, ok := s.next()
+		if !ok || it.token.Kind == lexer.EOF || (s.main && isDirectiveStart(it.token)) {
+			pp.errorf(diagnostic.PP_MACRO_ARGUMENTS, invocation, "unterminated argument list invoking macro \"%s\"", m.name)
+			if ok {
+				s.push([]item{it})
+			}
+			return nil, it, false
+		}
+
+		it.token.AtLineStart = false
+
+		switch {
+		case it.token.Kind == lexer.LPAREN:
+			depth++
+		case it.token.Kind == lexer.RPAREN && depth > 0:
+			depth--
+		case it.token.Kind == lexer.RPAREN:
+			return pp.checkArgs(m, invocation, args, it)
+		case it.token.Kind == lexer.COMMA && depth == 0 && (!m.isVariadic || len(args) <= len(m.params)):
+			args = append(args, []item{})
+			continue
+		}
+
+		args[len(args)-1] = append(args[len(args)-1], it)
+	}
+}
+
+func (pp *preprocessor) checkArgs(m *macro, invocation lexer.Token, args [][]item, rparen item) ([][]item, item, bool) {
+	expected := len(m.params)
+
+	// F() passes no argument to a macro without parameters
+	if expected == 0 && !m.isVariadic && len(args) == 1 && len(args[0]) == 0 {
+		return nil, rparen, true
+	}
+
+	// the variadic part can be omitted entirely
+	if m.isVariadic && len(args) == expected {
+		args = append(args, []item{})
+	}
+
+	if m.isVariadic {
+		expected++
+	}
+
+	if len(args) != expected {
+		pp.errorf(diagnostic.PP_MACRO_ARGUMENTS, invocation, "macro \"%s\" requires %d arguments, but %d given", m.name, expected, len(args))
+		return nil, rparen, false
+	}
+
+	return args, rparen, true
+}
+
+// substitute replaces the parameters of the macro body by the arguments and
+// applies the # and ## operators.
+func (pp *preprocessor) substitute(m *macro, body []lexer.Token, args [][]item, hide hideset, invocation lexer.Token) []item {
+	out := []item{}
+
+	for i := 0; i < len(body); i++ {
+		token := body[i]
+		index := m.paramIndex(token)
+		nextIsPaste := i+1 < len(body) && body[i+1].Kind == lexer.POUND_POUND
+
+		switch {
+		case token.Kind == lexer.POUND && m.isFunction && i+1 < len(body) && m.paramIndex(body[i+1]) >= 0:
+			str := pp.stringize(args[m.paramIndex(body[i+1])], token)
+			str.HasSpace = token.HasSpace
+			out = append(out, item{token: str})
+			i++
+
+		case token.Kind == lexer.POUND_POUND && i+1 < len(body):
+			last := i + 1 // index of the last token of the right operand
+			rhs := []item{{token: body[i+1]}}
+
+			if rhsIndex := m.paramIndex(body[i+1]); rhsIndex >= 0 {
+				rhs = args[rhsIndex]
+
+				// GNU extension: `, ## __VA_ARGS__` drops the comma when there are no variadic arguments
+				if m.isVariadic && rhsIndex == len(m.params) && len(out) > 0 && out[len(out)-1].token.Kind == lexer.COMMA {
+					if len(rhs) == 0 {
+						out = out[:len(out)-1]
+					} else {
+						out = append(out, pp.expandList(rhs)...)
+					}
+					i = last
+					continue
+				}
+			} else if m.isVariadic && body[i+1].Value == "__VA_OPT__" {
+				var end int
+				rhs, end = pp.vaOpt(m, body, i+1, args, hide, invocation)
+				last = end - 1
+			}
+
+			if len(rhs) == 0 {
+				rhs = []item{{token: lexer.Token{Kind: placemarker}}}
+			}
+
+			if len(out) == 0 {
+				out = append(out, rhs...)
+			} else {
+				out = append(out[:len(out)-1], pp.paste(out[len(out)-1], rhs[0])...)
+				out = append(out, rhs[1:]...)
+			}
+			i = last
+
+		case m.isVariadic && token.Kind == lexer.IDENTIFIER && token.Value == "__VA_OPT__":
+			expansion, end := pp.vaOpt(m, body, i, args, hide, invocation)
+			if len(expansion) == 0 && nextIsPaste {
+				expansion = []item{{token: lexer.Token{Kind: placemarker}}}
+			}
+			out = append(out, expansion...)
+			i = end - 1
+
+		case index >= 0 && nextIsPaste:
+			arg := args[index]
+			if len(arg) == 0 {
+				arg = []item{{token: lexer.Token{Kind: placemarker}}}
+			}
+			out = append(out, arg...)
+
+		case index >= 0:
+			expanded := pp.expandList(args[index])
+			if len(expanded) > 0 {
+				expanded[0].token.HasSpace = token.HasSpace
+			}
+			out = append(out, expanded...)
+
+		default:
+			out = append(out, item{token: token})
+		}
+	}
+
+	result := make([]item, 0, len(out))
+	for _, it := range out {
+		if it.token.Kind == placemarker {
+			continue
+		}
+
+		it.hide = it.hide.union(hide)
+		it.token.Start = invocation.Start
+		it.token.End = invocation.End
+		it.token.AtLineStart = false
+		it.token.Leading = nil
+		it.token.Trailing = nil
+		result = append(result, it)
+	}
+
+	if len(result) > 0 {
+		result[0].token.HasSpace = invocation.HasSpace
+		result[0].token.Leading = invocation.Leading
+	}
+
+	return result
+}
+
+// vaOpt expands `__VA_OPT__(content)` starting at body[start]. It returns the
+// expansion and the index following the closing parenthesis.
+func (pp *preprocessor) vaOpt(m *macro, body []lexer.Token, start int, args [][]item, hide hideset, invocation lexer.Token) ([]item, int) {
+	if start+1 >= len(body) || body[start+1].Kind != lexer.LPAREN {
+		pp.errorf(diagnostic.PP_INVALID_MACRO, body[start], "__VA_OPT__ must be followed by '('")
+		return nil, start + 1
+	}
+
+	depth := 0
+	end := start + 1
+	for ; end < len(body); end++ {
+		if body[end].Kind == lexer.LPAREN {
+			depth++
+		} else if body[end].Kind == lexer.RPAREN {
+			depth--
+			if depth == 0 {
+				break
+			}
+		}
+	}
+
+	if end >= len(body) {
+		pp.errorf(diagnostic.PP_INVALID_MACRO, body[start], "unterminated __VA_OPT__")
+		return nil, len(body)
+	}
+
+	if len(pp.expandList(args[len(m.params)])) == 0 {
+		return nil, end + 1
+	}
+
+	content := pp.substitute(m, body[start+2:end], args, hide, invocation)
+	return content, end + 1
+}
+
+// expandList fully macro-expands an isolated list of tokens.
+func (pp *preprocessor) expandList(items []item) []item {
+	s := &stream{items: items}
+	out := []item{}
+
+	for {
+		it, ok := s.next()
+		if !ok {
+			return out
+		}
+		if !pp.expand(s, it) {
+			out = append(out, it)
+		}
+	}
+}
+
+// stringize implements the # operator.
+func (pp *preprocessor) stringize(arg []item, at lexer.Token) lexer.Token {
+	var sb strings.Builder
+
+	sb.WriteByte('"')
+	for i, it := range arg {
+		if i > 0 && it.token.HasSpace {
+			sb.WriteByte(' ')
+		}
+
+		text := spelling(it.token)
+		if it.token.Kind == lexer.STRING || it.token.Kind == lexer.CHARACTER {
+			text = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text)
+		}
+		sb.WriteString(text)
+	}
+	sb.WriteByte('"')
+
+	token := at
+	token.Kind = lexer.STRING
+	token.Value = sb.String()
+	return token
+}
+
+// paste implements the ## operator. When the result is not a single valid
+// token the operands are kept separate.
+func (pp *preprocessor) paste(lhs, rhs item) []item {
+	if lhs.token.Kind == placemarker {
+		return []item{rhs}
+	}
+	if rhs.token.Kind == placemarker {
+		return []item{lhs}
+	}
+
+	text := spelling(lhs.token) + spelling(rhs.token)
+	tokens, diagnostics := lexer.Tokensize(text)
+
+	if len(diagnostics) > 0 || len(tokens) != 2 || tokens[0].Kind == lexer.INCLUDER {
+		pp.errorf(diagnostic.PP_INVALID_PASTE, lhs.token, "pasting \"%s\" and \"%s\" does not give a valid preprocessing token", spelling(lhs.token), spelling(rhs.token))
+		return []item{lhs, rhs}
+	}
+
+	token := lhs.token
+	token.Kind = tokens[0].Kind
+	token.Value = tokens[0].Value
+
+	return []item{{token: token, hide: lhs.hide.union(rhs.hide)}}
+}
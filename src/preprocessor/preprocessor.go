@@ -0,0 +1,491 @@
+package preprocessor
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ZiplEix/c_parser/src/diagnostic"
+	"github.com/ZiplEix/c_parser/src/lexer"
+)
+
+type Mode int
+
+// Enum for the different ways of handling the directives.
+const (
+	// EXPAND executes the directives and expands the macros.
+	EXPAND Mode = iota
+	// PASS_THROUGH keeps every directive line verbatim as a DIRECTIVE token and
+	// leaves the rest of the tokens untouched, for the C compiler to preprocess.
+	PASS_THROUGH
+)
+
+// Config holds the settings of a preprocessing run.
+type Config struct {
+	File    string            // name of the file, used by __FILE__
+	Source  string            // text the tokens come from, used to keep directives verbatim
+	Defines map[string]string // macros defined before the first line, like -DNAME=VALUE ("" means 1)
+	Mode    Mode
+	Version string // value of __STDC_VERSION__, 201710L when empty
+
+	// PreC23 reports #elifdef, #elifndef and __VA_OPT__ as errors, the
+	// headers being tokenized with lexer.Options.PreC23.
+	PreC23 bool
+
+	IncludePaths []string // directories searched for headers, like -I
+	SystemPaths  []string // directories searched for headers after IncludePaths, like -isystem
+}
+
+// condition is an entry of the #if/#ifdef stack.
+type condition struct {
+	active  bool // the current group is kept
+	taken   bool // one of the groups has already been kept
+	sawElse bool
+	parent  bool // the enclosing group is kept
+	start   lexer.Token
+}
+
+type preprocessor struct {
+	config      Config
+	macros      map[string]*macro
+	conditions  []condition
+	includes    *includes
+	output      []lexer.Token
+	diagnostics []diagnostic.Diagnostic
+	date        string
+	time        string
+	counter     int
+}
+
+// Preprocess runs the C preprocessor over tokens produced by lexer.Tokensize.
+// In EXPAND mode directives are executed and removed, macros are expanded and
+// the groups excluded by conditional compilation are dropped. In PASS_THROUGH
+// mode each directive becomes a single DIRECTIVE token. In both modes #include
+// lines are kept as INCLUDER/INCLUDE_PATH tokens for the parser, the INCLUDER
+// value being the whole line. When the header is found in the search paths,
+// its preprocessed tokens follow, between INCLUDE_START and INCLUDE_END tokens.
+func Preprocess(tokens []lexer.Token, config Config) ([]lexer.Token, []diagnostic.Diagnostic) {
+	pp := createPreprocessor(config)
+
+	if config.Mode == PASS_THROUGH {
+		pp.passThrough(newStream(tokens, true))
+	} else {
+		pp.run(newStream(tokens, true))
+	}
+
+	return pp.output, pp.diagnostics
+}
+
+// Preprocessor is the streaming form of Preprocess: it reads its input from a
+// lexer.TokenSource, like a lexer.Scanner, and produces the preprocessed
+// tokens on demand. It is itself a lexer.TokenSource.
+type Preprocessor struct {
+	pp     *preprocessor
+	stream *stream
+	done   bool
+}
+
+// New returns a Preprocessor reading from source. Without config.Source, the
+// DIRECTIVE tokens of the PASS_THROUGH mode are rebuilt from the tokens of the
+// line instead of holding its exact text.
+func New(source lexer.TokenSource, config Config) *Preprocessor {
+	return &Preprocessor{
+		pp:     createPreprocessor(config),
+		stream: newSourceStream(source),
+	}
+}
+
+// Next returns the next preprocessed token. Once the EOF token is reached it
+// is returned by every call.
+func (p *Preprocessor) Next() lexer.Token {
+	pp := p.pp
+
+	for len(pp.output) == 0 && !p.done {
+		if pp.config.Mode == PASS_THROUGH {
+			p.done = !pp.passThroughStep(p.stream)
+		} else {
+			p.done = !pp.step(p.stream)
+		}
+	}
+
+	token := pp.output[0]
+	if token.Kind != lexer.EOF || len(pp.output) > 1 {
+		pp.output = pp.output[1:]
+	}
+	return token
+}
+
+// Diagnostics returns the errors and warnings found in the tokens processed
+// so far. Appending to the result never changes the ones of the Preprocessor.
+func (p *Preprocessor) Diagnostics() []diagnostic.Diagnostic {
+	return slices.Clip(p.pp.diagnostics)
+}
+
+func createPreprocessor(config Config) *preprocessor {
+	now := time.Now()
+
+	pp := &preprocessor{
+		config:   config,
+		macros:   map[string]*macro{},
+		includes: newIncludes(),
+		date:     fmt.Sprintf("%q", now.Format("Jan _2 2006")),
+		time:     fmt.Sprintf("%q", now.Format("15:04:05")),
+	}
+
+	pp.defineBuiltins()
+
+	names := make([]string, 0, len(config.Defines))
+	for name := range config.Defines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := config.Defines[name]
+		if value == "" {
+			value = "1"
+		}
+		pp.defineFromString(name + " " + value)
+	}
+
+	return pp
+}
+
+// defineFromString runs `#define <definition>` as if it was at the top of the file.
+func (pp *preprocessor) defineFromString(definition string) {
+	tokens, diagnostics := lexer.Tokensize("#define " + definition + "\n")
+	pp.diagnostics = append(pp.diagnostics, diagnostics...)
+
+	sub := &preprocessor{config: pp.config, macros: pp.macros, includes: pp.includes}
+	sub.run(newStream(tokens, true))
+	pp.diagnostics = append(pp.diagnostics, sub.diagnostics...)
+}
+
+// run processes the stream until its EOF token, which is copied to the output.
+func (pp *preprocessor) run(s *stream) {
+	for pp.step(s) {
+	}
+}
+
+// step processes the next token of the stream, a whole line for directives.
+// It returns false once the EOF token has been copied to the output.
+func (pp *preprocessor) step(s *stream) bool {
+	it, _ := s.next()
+
+	if it.token.Kind == lexer.EOF {
+		for _, cond := range pp.conditions {
+			pp.errorf(diagnostic.PP_UNBALANCED_COND, cond.start, "unterminated conditional directive")
+		}
+		pp.conditions = nil
+		pp.output = append(pp.output, it.token)
+		return false
+	}
+
+	if isDirectiveStart(it.token) {
+		pp.directive(s, it.token)
+		return true
+	}
+
+	if pp.skipping() || pp.expand(s, it) {
+		return true
+	}
+
+	pp.output = append(pp.output, it.token)
+	return true
+}
+
+func (pp *preprocessor) skipping() bool {
+	return len(pp.conditions) > 0 && !pp.conditions[len(pp.conditions)-1].active
+}
+
+// isDirectiveStart reports whether the token starts a preprocessing directive.
+func isDirectiveStart(token lexer.Token) bool {
+	return token.AtLineStart && (token.Kind == lexer.POUND || token.Kind == lexer.INCLUDER)
+}
+
+// readLine returns the tokens up to the end of the current logical line.
+func (pp *preprocessor) readLine(s *stream) []item {
+	line := []item{}
+
+	for {
+		it, ok := s.peek()
+		if !ok || it.token.Kind == lexer.EOF || it.token.AtLineStart {
+			return line
+		}
+		s.next()
+		line = append(line, it)
+	}
+}
+
+func (pp *preprocessor) directive(s *stream, hash lexer.Token) {
+	if hash.Kind == lexer.INCLUDER {
+		line := pp.readLine(s)
+		if !pp.skipping() {
+			pp.include(hash, line)
+		}
+		return
+	}
+
+	line := pp.readLine(s)
+	if len(line) == 0 {
+		// null directive
+		return
+	}
+
+	name := line[0].token
+	args := line[1:]
+
+	switch spelling(name) {
+	case "if":
+		pp.pushCondition(name, !pp.skipping() && pp.evaluate(name, args))
+	case "ifdef", "ifndef":
+		active := false
+		if !pp.skipping() {
+			active = pp.isDefinedDirective(name, args) == (spelling(name) == "ifdef")
+		}
+		pp.pushCondition(name, active)
+	case "elif", "elifdef", "elifndef":
+		if spelling(name) != "elif" && pp.config.PreC23 {
+			pp.errorf(diagnostic.PP_C23_FEATURE, name, "#%s is a C23 feature", spelling(name))
+		}
+		pp.elif(name, args)
+	case "include":
+		if !pp.skipping() {
+			pp.computedInclude(hash, line, true)
+		}
+	case "else":
+		pp.elseDirective(name, args)
+	case "endif":
+		if len(pp.conditions) == 0 {
+			pp.errorf(diagnostic.PP_UNBALANCED_COND, name, "#endif without #if")
+			return
+		}
+		pp.extraTokens(name, args)
+		pp.conditions = pp.conditions[:len(pp.conditions)-1]
+	default:
+		if pp.skipping() {
+			return
+		}
+		pp.executeDirective(name, args)
+	}
+}
+
+// executeDirective runs the directives that only matter in kept groups.
+func (pp *preprocessor) executeDirective(name lexer.Token, args []item) {
+	switch spelling(name) {
+	case "define":
+		pp.define(name, args)
+	case "undef":
+		if len(args) == 0 || !isIdentifier(args[0].token) {
+			pp.errorf(diagnostic.PP_INVALID_MACRO, name, "macro name must be an identifier")
+			return
+		}
+		pp.extraTokens(name, args[1:])
+		delete(pp.macros, spelling(args[0].token))
+	case "error":
+		pp.errorf(diagnostic.PP_USER_ERROR, name, "#error %s", joinSpelling(args))
+	case "warning":
+		pp.warningf(diagnostic.PP_USER_WARNING, name, "#warning %s", joinSpelling(args))
+	case "include_next":
+		pp.errorf(diagnostic.PP_INVALID_DIRECTIVE, name, "#include_next is not supported")
+	case "pragma":
+		if len(args) > 0 && spelling(args[0].token) == "once" {
+			pp.pragmaOnce()
+		}
+	case "line", "ident", "sccs":
+		// nothing to do for the parser
+	default:
+		pp.errorf(diagnostic.PP_INVALID_DIRECTIVE, name, "invalid preprocessing directive #%s", spelling(name))
+	}
+}
+
+// passThrough copies the stream to the output, replacing each directive line
+// by a DIRECTIVE token holding its text.
+func (pp *preprocessor) passThrough(s *stream) {
+	for pp.passThroughStep(s) {
+	}
+}
+
+// passThroughStep copies the next token or directive of the stream to the
+// output. It returns false once the EOF token has been copied.
+func (pp *preprocessor) passThroughStep(s *stream) bool {
+	it, _ := s.next()
+
+	switch {
+	case it.token.Kind == lexer.EOF:
+		pp.output = append(pp.output, it.token)
+		return false
+	case it.token.Kind == lexer.INCLUDER && isDirectiveStart(it.token):
+		pp.include(it.token, pp.readLine(s))
+	case isDirectiveStart(it.token):
+		line := pp.readLine(s)
+		directive := it.token
+
+		if len(line) > 0 && spelling(line[0].token) == "include" {
+			// a macro naming the header cannot be resolved without expansion,
+			// it stays a plain directive
+			if _, _, ok := headerName(line[1:]); ok {
+				pp.computedInclude(directive, line, false)
+				return true
+			}
+		}
+		if len(line) > 1 && spelling(line[0].token) == "pragma" && spelling(line[1].token) == "once" {
+			pp.pragmaOnce()
+		}
+
+		last := directive
+		if len(line) > 0 {
+			last = line[len(line)-1].token
+		}
+
+		directive.Kind = lexer.DIRECTIVE
+		directive.Value = pp.rawText(directive, last, line)
+		directive.End = last.End
+		directive.Trailing = last.Trailing
+		pp.output = append(pp.output, directive)
+	default:
+		pp.output = append(pp.output, it.token)
+	}
+
+	return true
+}
+
+// rawText returns the source text from the first to the last token of a
+// directive line. Without source, the line is rebuilt from the tokens.
+func (pp *preprocessor) rawText(first, last lexer.Token, line []item) string {
+	source := pp.config.Source
+	if first.Start.Offset <= last.End.Offset && last.End.Offset <= len(source) {
+		return source[first.Start.Offset:last.End.Offset]
+	}
+
+	text := spelling(first)
+	if len(line) > 0 && line[0].token.HasSpace {
+		text += " "
+	}
+	return text + joinSpelling(line)
+}
+
+func (pp *preprocessor) pushCondition(start lexer.Token, active bool) {
+	pp.conditions = append(pp.conditions, condition{
+		active: active,
+		taken:  active,
+		parent: !pp.skipping(),
+		start:  start,
+	})
+}
+
+func (pp *preprocessor) elif(name lexer.Token, args []item) {
+	if len(pp.conditions) == 0 {
+		pp.errorf(diagnostic.PP_UNBALANCED_COND, name, "#%s without #if", spelling(name))
+		return
+	}
+
+	cond := &pp.conditions[len(pp.conditions)-1]
+	if cond.sawElse {
+		pp.errorf(diagnostic.PP_UNBALANCED_COND, name, "#%s after #else", spelling(name))
+	}
+
+	if !cond.parent || cond.taken {
+		cond.active = false
+		return
+	}
+
+	switch spelling(name) {
+	case "elif":
+		cond.active = pp.evaluate(name, args)
+	case "elifdef":
+		cond.active = pp.isDefinedDirective(name, args)
+	default:
+		cond.active = !pp.isDefinedDirective(name, args)
+	}
+	cond.taken = cond.active
+}
+
+func (pp *preprocessor) elseDirective(name lexer.Token, args []item) {
+	if len(pp.conditions) == 0 {
+		pp.errorf(diagnostic.PP_UNBALANCED_COND, name, "#else without #if")
+		return
+	}
+
+	cond := &pp.conditions[len(pp.conditions)-1]
+	if cond.sawElse {
+		pp.errorf(diagnostic.PP_UNBALANCED_COND, name, "#else after #else")
+	}
+
+	pp.extraTokens(name, args)
+	cond.sawElse = true
+	cond.active = cond.parent && !cond.taken
+	cond.taken = true
+}
+
+// isDefinedDirective evaluates the operand of #ifdef, #ifndef, #elifdef and
+// #elifndef.
+func (pp *preprocessor) isDefinedDirective(name lexer.Token, args []item) bool {
+	if len(args) == 0 || !isIdentifier(args[0].token) {
+		pp.errorf(diagnostic.PP_INVALID_MACRO, name, "macro name must be an identifier")
+		return false
+	}
+
+	pp.extraTokens(name, args[1:])
+	_, defined := pp.macros[spelling(args[0].token)]
+	return defined
+}
+
+func (pp *preprocessor) extraTokens(name lexer.Token, args []item) {
+	if len(args) > 0 {
+		pp.warningf(diagnostic.PP_EXTRA_TOKENS, args[0].token, "extra tokens at end of #%s directive", spelling(name))
+	}
+}
+
+func (pp *preprocessor) errorf(code string, at lexer.Token, format string, args ...any) {
+	span := diagnostic.Span{Start: at.Start, End: at.End}
+	pp.diagnostics = append(pp.diagnostics, diagnostic.Errorf(code, span, format, args...))
+}
+
+func (pp *preprocessor) warningf(code string, at lexer.Token, format string, args ...any) {
+	span := diagnostic.Span{Start: at.Start, End: at.End}
+	pp.diagnostics = append(pp.diagnostics, diagnostic.Warningf(code, span, format, args...))
+}
+
+// spelling returns the source text of a token.
+func spelling(token lexer.Token) string {
+	if token.Kind == lexer.INCLUDER {
+		return "#include"
+	}
+	return token.Value
+}
+
+// joinSpelling rebuilds the text of a sequence of tokens, with a single space
+// where the source had whitespace.
+func joinSpelling(items []item) string {
+	var sb strings.Builder
+
+	for i, it := range items {
+		if i > 0 && it.token.HasSpace {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(spelling(it.token))
+	}
+
+	return sb.String()
+}
+
+// isIdentifier reports whether the token can be used as a macro name:
+// identifiers and keywords.
+func isIdentifier(token lexer.Token) bool {
+	value := token.Value
+	if value == "" || token.Kind == lexer.STRING || token.Kind == lexer.CHARACTER {
+		return false
+	}
+
+	for i, c := range []byte(value) {
+		isLetter := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		if !isLetter && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,164 @@
+package preprocessor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ZiplEix/c_parser/src/diagnostic"
+	"github.com/ZiplEix/c_parser/src/lexer"
+)
+
+// spellings returns the spelling of the tokens separated by single spaces,
+// so outputs are compared independently of their whitespace.
+func spellings(tokens []lexer.Token) string {
+	words := []string{}
+	for _, token := range tokens {
+		if token.Kind != lexer.EOF {
+			words = append(words, spelling(token))
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func TestPreprocess(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			// C17 6.10.3.3 EXAMPLE
+			name: "hash_hash",
+			source: `#define hash_hash # ## #
+#define mkstr(a) # a
+#define in_between(a) mkstr(a)
+#define join(c, d) in_between(c hash_hash d)
+char p[] = join(x, y);
+`,
+			want: `char p[] = "x ## y";`,
+		},
+		{
+			// C17 6.10.3.5 EXAMPLE 3
+			name: "rescanning and hidesets",
+			source: `#define x 3
+#define f(a) f(x * (a))
+#undef x
+#define x 2
+#define g f
+#define z z[0]
+#define h g(~
+#define m(a) a(w)
+#define w 0,1
+#define t(a) a
+#define p() int
+#define q(x) x
+#define r(x,y) x ## y
+#define str(x) # x
+f(y+1) + f(f(z)) % t(t(g)(0) + t)(1);
+g(x+(3,4)-w) | h 5) & m
+(f)^m(m);
+p() i[q()] = { q(1), r(2,3), r(4,), r(,5), r(,) };
+char c[2][6] = { str(hello), str() };
+`,
+			want: `f(2 * (y+1)) + f(2 * (f(2 * (z[0])))) % f(2 * (0)) + t(1);
+f(2 * (2+(3,4)-0,1)) | f(2 * (~ 5)) & f(2 * (0,1))^m(0,1);
+int i[] = { 1, 23, 4, 5, };
+char c[2][6] = { "hello", "" };`,
+		},
+		{
+			// C17 6.10.3.5 EXAMPLE 4, `@\n` being replaced by a valid token
+			name: "str and xstr",
+			source: `#define str(s) # s
+#define xstr(s) str(s)
+#define debug(s, t) printf("x" # s "= %d, x" # t "= %s", \
+ x ## s, x ## t)
+#define INCFILE(n) vers ## n
+#define glue(a, b) a ## b
+#define xglue(a, b) glue(a, b)
+#define HIGHLOW "hello"
+#define LOW LOW ", world"
+debug(1, 2);
+fputs(str(strncmp("abc\0d", "abc", '\4') // this goes away
+ == 0) str(: end), s);
+xstr(INCFILE(2).h)
+glue(HIGH, LOW);
+xglue(HIGH, LOW)
+`,
+			want: `printf("x" "1" "= %d, x" "2" "= %s", x1, x2);
+fputs("strncmp(\"abc\\0d\", \"abc\", '\\4') == 0" ": end", s);
+"vers2.h"
+"hello";
+"hello" ", world"`,
+		},
+		{
+			name: "comma paste with empty __VA_ARGS__",
+			source: `#define eprintf(format, ...) fprintf(stderr, format, ## __VA_ARGS__)
+eprintf("a");
+eprintf("b", 1, 2);
+`,
+			want: `fprintf(stderr, "a");
+fprintf(stderr, "b", 1, 2);`,
+		},
+		{
+			name: "#if with a conditional operator",
+			source: `#if (1 ? 2 : 3) == 2 && (0 ? 1 / 0 : 1)
+yes
+#else
+no
+#endif
+`,
+			want: `yes`,
+		},
+		{
+			name: "#if with an unsigned comparison",
+			source: `#if -1 > 0u
+unsigned
+#endif
+#if -1 > 0
+signed
+#endif
+`,
+			want: `unsigned`,
+		},
+		{
+			name: "#elif chain",
+			source: `#define V 2
+#if V == 1
+one
+#elif V == 2
+two
+#elif V == 2
+again
+#else
+other
+#endif
+#if V == 3
+three
+#elif defined(W)
+w
+#else
+fallback
+#endif
+`,
+			want: `two fallback`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tokens, lexDiagnostics := lexer.Tokensize(test.source)
+			output, diagnostics := Preprocess(tokens, Config{Source: test.source})
+
+			for _, d := range append(lexDiagnostics, diagnostics...) {
+				if d.Severity == diagnostic.ERROR {
+					t.Errorf("unexpected error: %v", d)
+				}
+			}
+
+			want, _ := lexer.Tokensize(test.want)
+			if got, want := spellings(output), spellings(want); got != want {
+				t.Errorf("got\n\t%s\nwant\n\t%s", got, want)
+			}
+		})
+	}
+}
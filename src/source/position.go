@@ -0,0 +1,31 @@
+package source
+
+import "fmt"
+
+// Position is a location inside a source file. Offset is the byte offset from
+// the start of the file, Line and Col are 1-based (Col counts bytes).
+type Position struct {
+	File   string
+	Offset int
+	Line   int
+	Col    int
+}
+
+// IsValid reports whether the position has been set.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns the position as "file:line:col", or "line:col" when the
+// file name is unknown.
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Col)
+	}
+
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Col)
+}